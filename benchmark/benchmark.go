@@ -0,0 +1,201 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"time"
+	"trading-bot/brokers"
+	"trading-bot/brokers/backtesting"
+	"trading-bot/common"
+	"trading-bot/traders"
+)
+
+var log = common.NewLogger("benchmark")
+
+const (
+	BuyAndHoldName  = "BuyAndHold"
+	RandomEntryName = "RandomEntry"
+)
+
+// Summary aggregates the trades of a whole backtest.
+type Summary struct {
+	TotalTrades    int
+	WinRate        float64 // in percent
+	NetPnL         float64
+	ExpectedValueR float64
+}
+
+func Summarize(trades []*backtesting.Trade) Summary {
+	var summary Summary
+	var winningTrades int
+	var totalR float64
+
+	for _, trade := range trades {
+		summary.TotalTrades++
+		summary.NetPnL += trade.PnL
+		totalR += trade.RMultiple
+		if trade.PnL > 0 {
+			winningTrades++
+		}
+	}
+
+	if summary.TotalTrades > 0 {
+		summary.WinRate = float64(winningTrades) / float64(summary.TotalTrades) * 100
+		summary.ExpectedValueR = totalR / float64(summary.TotalTrades)
+	}
+
+	return summary
+}
+
+// Result is the outcome of a benchmark backtest.
+type Result struct {
+	Name    string
+	Metrics map[common.Month]*backtesting.Metrics
+	Trades  []*backtesting.Trade
+	Summary Summary
+}
+
+// Comparison is the performance of a strategy relative to a baseline.
+type Comparison struct {
+	Baseline       string
+	NetPnL         float64 // Strategy NetPnL - baseline NetPnL
+	WinRate        float64 // Strategy WinRate - baseline WinRate (in percent points)
+	ExpectedValueR float64 // Strategy ExpectedValueR - baseline ExpectedValueR
+}
+
+func Compare(strategy Summary, baseline *Result) *Comparison {
+	return &Comparison{
+		Baseline:       baseline.Name,
+		NetPnL:         strategy.NetPnL - baseline.Summary.NetPnL,
+		WinRate:        strategy.WinRate - baseline.Summary.WinRate,
+		ExpectedValueR: strategy.ExpectedValueR - baseline.Summary.ExpectedValueR,
+	}
+}
+
+// RunAll runs every baseline on the dataset, with the same broker configuration as the strategy.
+// The random entry baseline copies the frequency, directions, stop/target distances and sizing of strategyTrades.
+func RunAll(config *backtesting.Config, dataset *backtesting.Dataset, strategyTrades []*backtesting.Trade, seed int64) ([]*Result, error) {
+	buyAndHold, err := BuyAndHold(config, dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	randomEntry, err := RandomEntry(config, dataset, strategyTrades, seed)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Result{buyAndHold, randomEntry}, nil
+}
+
+func BuyAndHold(config *backtesting.Config, dataset *backtesting.Dataset) (*Result, error) {
+	buyAndHoldConfig := &traders.BuyAndHoldConfig{GapCloses: gapCloses(dataset)}
+
+	return run(BuyAndHoldName, config, dataset, func(broker brokers.Broker) {
+		traders.SetupBuyAndHoldTrader(broker, buyAndHoldConfig)
+	})
+}
+
+// gapCloses returns the times of the last candles before the candles with a data gap, where positions are canceled.
+func gapCloses(dataset *backtesting.Dataset) map[time.Time]bool {
+	closes := make(map[time.Time]bool)
+
+	var lastBucket, previousEnd, end time.Time // previousEnd is the time of the last tick of the previous candle
+	for tick := range dataset.Ticks() {
+		bucket := tick.GetTimestamp().Truncate(time.Minute)
+		if bucket != lastBucket {
+			previousEnd = end
+			lastBucket = bucket
+		}
+		end = tick.GetTimestamp()
+
+		if tick.GetIsGap() && !previousEnd.IsZero() {
+			closes[previousEnd] = true
+		}
+	}
+
+	return closes
+}
+
+func RandomEntry(config *backtesting.Config, dataset *backtesting.Dataset, strategyTrades []*backtesting.Trade, seed int64) (*Result, error) {
+	randomConfig := &traders.RandomEntryConfig{
+		Probability: entryProbability(dataset, strategyTrades),
+		Templates:   make([]traders.OrderTemplate, 0, len(strategyTrades)),
+		Seed:        seed,
+	}
+
+	for _, trade := range strategyTrades {
+		randomConfig.Templates = append(randomConfig.Templates, traders.OrderTemplate{
+			Direction:      trade.Direction,
+			StopDistance:   math.Abs(trade.OpenPrice - trade.StopLoss),
+			TargetDistance: math.Abs(trade.TakeProfit - trade.OpenPrice),
+			Quantity:       trade.Quantity,
+		})
+	}
+
+	log.Debug("Random entry: probability=%.6f, templates=%d, seed=%d", randomConfig.Probability, len(randomConfig.Templates), seed)
+
+	return run(RandomEntryName, config, dataset, func(broker brokers.Broker) {
+		traders.SetupRandomEntryTrader(broker, randomConfig)
+	})
+}
+
+// entryProbability computes the per-candle probability of entry that gives the same number of trades as the strategy,
+// taking into account that no position is taken while one is open.
+func entryProbability(dataset *backtesting.Dataset, trades []*backtesting.Trade) float64 {
+	if len(trades) == 0 {
+		return 0
+	}
+
+	var candles int
+	var lastBucket time.Time
+	for tick := range dataset.Ticks() {
+		bucket := tick.GetTimestamp().Truncate(time.Minute)
+		if bucket != lastBucket {
+			candles++
+			lastBucket = bucket
+		}
+	}
+
+	var inTrade int
+	for _, trade := range trades {
+		inTrade += int(trade.CloseTime.Sub(trade.OpenTime) / time.Minute)
+	}
+
+	freeCandles := candles - inTrade
+	if freeCandles <= len(trades) {
+		return 1
+	}
+
+	return float64(len(trades)) / float64(freeCandles)
+}
+
+func run(name string, config *backtesting.Config, dataset *backtesting.Dataset, setup func(broker brokers.Broker)) (*Result, error) {
+	broker, err := backtesting.NewBroker(config, dataset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create broker: %w", err)
+	}
+
+	setup(broker)
+
+	if err := broker.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run broker: %w", err)
+	}
+
+	metrics, err := backtesting.ComputeMetrics(broker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute metrics: %w", err)
+	}
+
+	trades, err := backtesting.GetAllTrades(broker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trades: %w", err)
+	}
+
+	return &Result{
+		Name:    name,
+		Metrics: metrics,
+		Trades:  trades,
+		Summary: Summarize(trades),
+	}, nil
+}
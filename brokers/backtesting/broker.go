@@ -1,20 +1,29 @@
 package backtesting
 
 import (
+	"cmp"
 	"fmt"
 	"math"
 	"slices"
 	"time"
 	"trading-bot/brokers"
 	"trading-bot/common"
+	stats "trading-bot/metrics"
 )
 
 var log = common.NewLogger("backtesting")
 
 type Config struct {
-	LotSize        int     // Size of the lot to trade
-	Leverage       float64 // Leverage to use for trading
-	InitialCapital float64 // Initial capital for the backtesting account
+	LotSize        int       // Size of the lot to trade
+	Leverage       float64   // Leverage to use for trading
+	InitialCapital float64   // Initial capital for the backtesting account
+	Costs          CostModel // Trading costs on top of the bid/ask spread
+}
+
+// CostModel describes trading costs on top of the bid/ask spread of the data.
+type CostModel struct {
+	CommissionPerLot float64 // Commission per lot and per side (open and close), in account currency
+	Slippage         float64 // Price slippage against the position on open and close
 }
 
 type Metrics struct {
@@ -51,6 +60,10 @@ type Metrics struct {
 
 	// ShortTrades is the number of trades taken in the short (sell) direction.
 	ShortTrades int
+
+	// Significance holds the statistical significance of the trades.
+	// Tells whether ExpectedValueR is distinguishable from zero.
+	stats.Significance
 }
 
 // Trade represents a completed trade with all its details
@@ -60,11 +73,15 @@ type Trade struct {
 	CloseTime  time.Time                 // Time when the trade was closed
 	OpenPrice  float64                   // Price at which the trade was opened
 	ClosePrice float64                   // Price at which the trade was closed
-	StopLoss   float64                   // Stop loss price level
+	StopLoss   float64                   // Initial stop loss price level, the risk of the trade
 	TakeProfit float64                   // Take profit price level
 	Quantity   int                       // Number of lots/units traded
 	PnL        float64                   // Profit and Loss in account currency
 	RMultiple  float64                   // Risk-adjusted return (PnL / initial risk)
+
+	Reason        string               // Reason of the order that opened the trade
+	ReasonDetails *brokers.OrderReason // Structured reason, e.g. strategy state at entry (optional)
+	CloseReason   string               // e.g., stop loss, take profit, or the exit rule of the trader
 }
 
 type broker struct {
@@ -74,7 +91,9 @@ type broker struct {
 	capital          float64
 	openPositions    map[*position]struct{}
 	callbacks        map[brokers.Timeframe][]func(candle brokers.Candle)
+	timeframes       []brokers.Timeframe // Of the callbacks, from the highest
 	positionsHistory []*position
+	tradingStart     time.Time // Candles before are warm-up candles
 }
 
 // Run implements brokers.BacktestingBroker.
@@ -121,12 +140,22 @@ func (b *broker) GetCurrentTime() time.Time {
 
 // RegisterMarketDataCallback implements brokers.Broker.
 func (b *broker) RegisterMarketDataCallback(timeframe brokers.Timeframe, callback func(candle brokers.Candle)) {
+	if _, exists := b.callbacks[timeframe]; !exists {
+		b.timeframes = append(b.timeframes, timeframe)
+		slices.SortFunc(b.timeframes, func(x, y brokers.Timeframe) int {
+			return cmp.Compare(y, x) // Highest first
+		})
+	}
 	b.callbacks[timeframe] = append(b.callbacks[timeframe], callback)
 }
 
 // PlaceOrder implements brokers.Broker.
 func (b *broker) PlaceOrder(order *brokers.Order) (brokers.Position, error) {
-	pos := newPosition(b.currentTick(), b.GetCapital(), order)
+	if b.warmingUp() {
+		return nil, fmt.Errorf("cannot place order during warm-up, trading starts at %s", b.tradingStart.Format("2006-01-02 15:04:05"))
+	}
+
+	pos := newPosition(b.currentTick(), b.GetCapital(), order, &b.config.Costs)
 	margin := pos.getMargin(b.GetLeverage())
 
 	if margin > b.capital {
@@ -144,6 +173,47 @@ func (b *broker) PlaceOrder(order *brokers.Order) (brokers.Position, error) {
 	return pos, nil
 }
 
+// ClosePosition implements brokers.Broker.
+func (b *broker) ClosePosition(p brokers.Position, reason string) error {
+	pos, ok := p.(*position)
+	if !ok {
+		return fmt.Errorf("invalid position type: %T", p)
+	}
+	if _, open := b.openPositions[pos]; !open {
+		return fmt.Errorf("position is not open")
+	}
+
+	b.closePosition(pos, reason)
+
+	log.Debug("📉 Position closed (%s) at %s: Direction=%s, Quantity=%d, OpenPrice=%.5f, ClosePrice=%.5f",
+		reason,
+		pos.closeTime.Format("2006-01-02 15:04:05"),
+		pos.direction, pos.quantity, pos.openPrice, pos.closePrice)
+
+	return nil
+}
+
+// SetStopLoss implements brokers.Broker.
+func (b *broker) SetStopLoss(p brokers.Position, stopLoss float64) error {
+	pos, ok := p.(*position)
+	if !ok {
+		return fmt.Errorf("invalid position type: %T", p)
+	}
+	if _, open := b.openPositions[pos]; !open {
+		return fmt.Errorf("position is not open")
+	}
+
+	// Like a real broker, reject a stop that would close the position at once
+	price := getClosePrice(pos.direction, b.currentTick())
+	if (pos.direction == brokers.PositionDirectionLong && stopLoss >= price) ||
+		(pos.direction == brokers.PositionDirectionShort && stopLoss <= price) {
+		return fmt.Errorf("stop loss %.5f is beyond the price %.5f of the %s position", stopLoss, price, pos.direction)
+	}
+
+	pos.stopLoss = stopLoss
+	return nil
+}
+
 var _ brokers.Broker = (*broker)(nil)
 var _ brokers.BacktestingBroker = (*broker)(nil)
 
@@ -162,6 +232,18 @@ func NewBroker(config *Config, dataset *Dataset) (brokers.BacktestingBroker, err
 	return b, nil
 }
 
+// SetTradingStart makes the candles before start warm-up candles: traders fill their history
+// but do not trade, and orders are rejected.
+func SetTradingStart(b brokers.BacktestingBroker, start time.Time) error {
+	bb, ok := b.(*broker)
+	if !ok {
+		return fmt.Errorf("invalid broker type: expected *broker, got %T", b)
+	}
+
+	bb.tradingStart = start
+	return nil
+}
+
 func ComputeMetrics(b brokers.BacktestingBroker) (map[common.Month]*Metrics, error) {
 	bb, ok := b.(*broker)
 	if !ok {
@@ -186,7 +268,7 @@ func GetAllTrades(b brokers.BacktestingBroker) ([]*Trade, error) {
 		}
 
 		pnl := pos.getProfitAndLoss()
-		risk := math.Abs(pos.openPrice - pos.stopLoss)
+		risk := math.Abs(pos.openPrice - pos.initialStopLoss)
 		var rMultiple float64
 		if risk > 0 {
 			rMultiple = pnl / (risk * float64(pos.quantity))
@@ -198,11 +280,15 @@ func GetAllTrades(b brokers.BacktestingBroker) ([]*Trade, error) {
 			CloseTime:  pos.closeTime,
 			OpenPrice:  pos.openPrice,
 			ClosePrice: pos.closePrice,
-			StopLoss:   pos.stopLoss,
+			StopLoss:   pos.initialStopLoss,
 			TakeProfit: pos.takeProfit,
 			Quantity:   pos.quantity,
 			PnL:        pnl,
 			RMultiple:  rMultiple,
+
+			Reason:        pos.reason,
+			ReasonDetails: pos.reasonDetails,
+			CloseReason:   pos.closeReason,
 		})
 	}
 
@@ -213,6 +299,10 @@ func (b *broker) currentTick() *tick {
 	return &b.ticks[b.currentIndex]
 }
 
+func (b *broker) warmingUp() bool {
+	return b.currentTick().Timestamp.Before(b.tradingStart)
+}
+
 func (b *broker) printGap() {
 	currentTick := b.currentTick()
 	if !currentTick.IsGap || b.currentIndex == 0 {
@@ -239,30 +329,29 @@ func (b *broker) processTick() {
 	}
 
 	for pos := range b.openPositions {
-		switch pos.isTriggered(currentTick) {
+		switch trigger := pos.isTriggered(currentTick); trigger {
 		case CloseTriggerNone:
 			// Position is still open, do nothing
 			continue
 		case CloseTriggerStopLoss, CloseTriggerTakeProfit:
 			// Position should be closed
-			b.closePosition(pos)
-
-			closeReason := "unknown"
-			if pos.isTriggered(currentTick) == CloseTriggerStopLoss {
-				closeReason = "stop loss"
-			} else if pos.isTriggered(currentTick) == CloseTriggerTakeProfit {
-				closeReason = "take profit"
+			reason := trigger.String()
+			if trigger == CloseTriggerStopLoss && pos.stopLoss != pos.initialStopLoss {
+				reason = "moved stop loss"
 			}
+			b.closePosition(pos, reason)
 
 			log.Debug("📉 Position closed (%s) at %s: Direction=%s, Quantity=%d, OpenPrice=%.5f, ClosePrice=%.5f",
-				closeReason,
+				reason,
 				currentTick.Timestamp.Format("2006-01-02 15:04:05"),
 				pos.direction, pos.quantity, pos.openPrice, pos.closePrice)
 		}
 	}
 
-	// Check if we have a full candle for any registered timeframes
-	for timeframe, callbacks := range b.callbacks {
+	// Check if we have a full candle for any registered timeframes, the higher first:
+	// lower timeframes closing at the same time see the new candles of the higher ones
+	for _, timeframe := range b.timeframes {
+		callbacks := b.callbacks[timeframe]
 		candle := b.tryCandle(timeframe)
 
 		if candle != nil {
@@ -331,6 +420,7 @@ func (b *broker) tryCandle(timeframe brokers.Timeframe) *brokers.Candle {
 		High:   high,
 		Low:    low,
 		Usable: usable,
+		WarmUp: b.warmingUp(),
 	}
 }
 
@@ -359,7 +449,7 @@ func (b *broker) cancelAllOpenPositions() {
 
 func (b *broker) closeAllOpenPositions() {
 	for pos := range b.openPositions {
-		b.closePosition(pos)
+		b.closePosition(pos, "end of test")
 
 		log.Debug("📉 Position closed (end of test) at %s: Direction=%s, Quantity=%d, OpenPrice=%.5f, ClosePrice=%.5f",
 			b.currentTick().Timestamp.Format("2006-01-02 15:04:05"),
@@ -367,8 +457,8 @@ func (b *broker) closeAllOpenPositions() {
 	}
 }
 
-func (b *broker) closePosition(pos *position) {
-	pos.closePosition(b.currentTick())
+func (b *broker) closePosition(pos *position, reason string) {
+	pos.closePosition(b.currentTick(), reason)
 	delete(b.openPositions, pos)
 
 	b.capital += pos.getMargin(b.GetLeverage())
@@ -446,6 +536,8 @@ func (b *broker) computeMonthlyMetrics(positions []*position) *Metrics {
 	var totalTrades, winningTrades, longTrades, shortTrades int
 	var netPnL, grossProfit, grossLoss, totalR, maxR float64
 	var totalDuration time.Duration
+	var rMultiples []float64
+	var wins []bool
 
 	equity := 0.0
 	peakEquity := 0.0
@@ -483,16 +575,18 @@ func (b *broker) computeMonthlyMetrics(positions []*position) *Metrics {
 		}
 
 		// R-multiple
-		risk := math.Abs(pos.openPrice - pos.stopLoss)
+		risk := math.Abs(pos.openPrice - pos.initialStopLoss)
 		if risk > 0 {
 			r := pnl / (risk * float64(pos.quantity))
 			totalR += r
+			rMultiples = append(rMultiples, r)
 			if r > maxR {
 				maxR = r
 			}
 		}
 
 		// Profit stats
+		wins = append(wins, pnl > 0)
 		if pnl > 0 {
 			winningTrades++
 			grossProfit += pnl
@@ -522,5 +616,7 @@ func (b *broker) computeMonthlyMetrics(positions []*position) *Metrics {
 		metrics.MaxDrawdownPct = (maxDrawdown / peakEquity) * 100
 	}
 
+	metrics.Significance = stats.ComputeSignificance(rMultiples, wins)
+
 	return metrics
 }
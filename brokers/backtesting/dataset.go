@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"path"
 	"runtime"
+	"slices"
 	"time"
 	"trading-bot/common"
+	"unsafe"
 
 	"github.com/xitongsys/parquet-go-source/local"
 	"github.com/xitongsys/parquet-go/reader"
@@ -41,6 +43,25 @@ func (d *Dataset) TickCount() int {
 	return len(d.ticks)
 }
 
+// From returns the dataset starting at the given time. Ticks are shared, not copied.
+func (d *Dataset) From(begin time.Time) *Dataset {
+	index, _ := slices.BinarySearchFunc(d.ticks, begin, func(t tick, begin time.Time) int {
+		return t.Timestamp.Compare(begin)
+	})
+
+	return &Dataset{
+		ticks:     d.ticks[index:],
+		symbol:    d.symbol,
+		beginDate: begin,
+		endDate:   d.endDate,
+	}
+}
+
+// MemorySize is the approximate size of the ticks in memory, in bytes.
+func (d *Dataset) MemorySize() int64 {
+	return int64(len(d.ticks)) * int64(unsafe.Sizeof(tick{}))
+}
+
 func (d *Dataset) Ticks() func(yield func(Tick) bool) {
 	return func(yield func(Tick) bool) {
 		for _, tick := range d.ticks {
@@ -168,8 +189,12 @@ type file struct {
 	reader *reader.ParquetReader
 }
 
+func dataFile(dataSource DataSource, year int, month int, symbol string) string {
+	return path.Join(dataPath, string(dataSource), fmt.Sprintf("%s_%04d%02d.parquet", symbol, year, month))
+}
+
 func openFile(dataSource DataSource, year int, month int, symbol string) (*file, error) {
-	parquetFile := path.Join(dataPath, string(dataSource), fmt.Sprintf("%s_%04d%02d.parquet", symbol, year, month))
+	parquetFile := dataFile(dataSource, year, month, symbol)
 
 	// Open Parquet file
 	pFile, err := local.NewLocalFileReader(parquetFile)
@@ -12,15 +12,22 @@ type position struct {
 	openPrice float64
 	openTime  time.Time
 	capital   float64 // Account capital at the time of opening
+	costs     *CostModel
 
 	// Close trigger details
-	stopLoss   float64
-	takeProfit float64
+	stopLoss        float64
+	initialStopLoss float64 // Risk of the position, for the R-multiple
+	takeProfit      float64
+
+	// Order reason
+	reason        string
+	reasonDetails *brokers.OrderReason
 
 	// Close position details
-	closePrice float64
-	closeTime  time.Time
-	closed     bool
+	closePrice  float64
+	closeTime   time.Time
+	closeReason string
+	closed      bool
 
 	// Backtesting specific
 	canceled bool
@@ -65,19 +72,39 @@ func (p *position) Canceled() bool {
 	return p.canceled
 }
 
+// StopLoss implements brokers.Position.
+func (p *position) StopLoss() float64 {
+	return p.stopLoss
+}
+
+// InitialStopLoss implements brokers.Position.
+func (p *position) InitialStopLoss() float64 {
+	return p.initialStopLoss
+}
+
+// CloseReason implements brokers.Position.
+func (p *position) CloseReason() string {
+	return p.closeReason
+}
+
 var _ brokers.Position = (*position)(nil)
 
-func newPosition(currentTick *tick, capital float64, order *brokers.Order) *position {
+func newPosition(currentTick *tick, capital float64, order *brokers.Order, costs *CostModel) *position {
 
 	return &position{
 		direction: order.Direction,
 		quantity:  order.Quantity,
-		openPrice: getOpenPrice(order.Direction, currentTick),
+		openPrice: applySlippage(order.Direction, getOpenPrice(order.Direction, currentTick), costs.Slippage),
 		openTime:  currentTick.Timestamp,
 		capital:   capital,
+		costs:     costs,
+
+		stopLoss:        order.StopLoss,
+		initialStopLoss: order.StopLoss,
+		takeProfit:      order.TakeProfit,
 
-		stopLoss:   order.StopLoss,
-		takeProfit: order.TakeProfit,
+		reason:        order.Reason,
+		reasonDetails: order.ReasonDetails,
 	}
 }
 
@@ -89,6 +116,17 @@ const (
 	CloseTriggerTakeProfit
 )
 
+func (t CloseTrigger) String() string {
+	switch t {
+	case CloseTriggerStopLoss:
+		return "stop loss"
+	case CloseTriggerTakeProfit:
+		return "take profit"
+	default:
+		return "none"
+	}
+}
+
 // isTriggered checks if the position should be closed based on the current tick.
 func (pos *position) isTriggered(currentTick *tick) CloseTrigger {
 	price := getClosePrice(pos.direction, currentTick)
@@ -122,9 +160,10 @@ func (pos *position) isTriggered(currentTick *tick) CloseTrigger {
 	}
 }
 
-func (pos *position) closePosition(currentTick *tick) {
-	pos.closePrice = getClosePrice(pos.direction, currentTick)
+func (pos *position) closePosition(currentTick *tick, reason string) {
+	pos.closePrice = applySlippage(pos.direction, getClosePrice(pos.direction, currentTick), -pos.costs.Slippage)
 	pos.closeTime = currentTick.Timestamp
+	pos.closeReason = reason
 	pos.closed = true
 }
 
@@ -167,6 +206,21 @@ func getClosePrice(direction brokers.PositionDirection, currentTick *tick) float
 	}
 }
 
+// applySlippage moves the price against the position: a positive slippage is paid on open, a negative one on close.
+func applySlippage(direction brokers.PositionDirection, price float64, slippage float64) float64 {
+	switch direction {
+
+	case brokers.PositionDirectionLong:
+		return price + slippage
+
+	case brokers.PositionDirectionShort:
+		return price - slippage
+
+	default:
+		panic("invalid position direction: " + direction.String())
+	}
+}
+
 func (pos *position) getMargin(leverage float64) float64 {
 	totalAmount := float64(pos.Quantity()) * pos.openPrice
 	margin := totalAmount / leverage
@@ -184,5 +238,6 @@ func (pos *position) getProfitAndLoss() float64 {
 		diff = -diff
 	}
 	totalAmount := float64(pos.Quantity()) * diff
-	return totalAmount
+	commission := 2 * pos.costs.CommissionPerLot * float64(pos.Quantity())
+	return totalAmount - commission
 }
@@ -0,0 +1,34 @@
+package backtesting
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"trading-bot/common"
+)
+
+// EngineVersion is the version of the backtesting engine.
+// Increment it on any change that alters backtest results (broker, metrics, indicators, traders...),
+// so that results cached by previous versions are not reused.
+const EngineVersion = 1
+
+// DataChecksum returns the checksum of the data file of a month, or "missing" if there is no such file.
+func DataChecksum(dataSource DataSource, month common.Month, symbol string) (string, error) {
+	f, err := os.Open(dataFile(dataSource, month.Year(), month.Month(), symbol))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "missing", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
@@ -1,6 +1,9 @@
 package brokers
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Timeframe time.Duration
 
@@ -29,12 +32,44 @@ func (t Timeframe) Format() string {
 	}
 }
 
+// Timeframes are the supported timeframes, from the lowest to the highest.
+var Timeframes = []Timeframe{Timeframe1Minute, Timeframe5Minutes, Timeframe15Minutes, Timeframe1Hour, Timeframe4Hour}
+
+// String returns the short name of the timeframe (e.g., M15, H4).
+func (t Timeframe) String() string {
+	switch t {
+	case Timeframe1Minute:
+		return "M1"
+	case Timeframe5Minutes:
+		return "M5"
+	case Timeframe15Minutes:
+		return "M15"
+	case Timeframe1Hour:
+		return "H1"
+	case Timeframe4Hour:
+		return "H4"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTimeframe parses the short name of a timeframe (e.g., M15, H4).
+func ParseTimeframe(name string) (Timeframe, error) {
+	for _, timeframe := range Timeframes {
+		if timeframe.String() == name {
+			return timeframe, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown timeframe %q, expected M1, M5, M15, H1 or H4", name)
+}
+
 type Candle struct {
 	Open   float64
 	Close  float64
 	High   float64
 	Low    float64
 	Usable bool // Backtesting only: Indicates if the candle is usable for trading
+	WarmUp bool // Backtesting only: Indicates if the candle only warms up the trader (history, indicators), without trading
 }
 
 type PositionDirection int
@@ -78,6 +113,18 @@ type Order struct {
 
 	// Reason for the order
 	Reason string
+
+	// Structured reason for the order, e.g. the state of the strategy at entry (optional)
+	ReasonDetails *OrderReason
+}
+
+// OrderReason is a node of a structured order reason.
+// Conditions carry their boolean result, indicators carry their current value.
+type OrderReason struct {
+	Label    string         `json:"label"`
+	Result   *bool          `json:"result,omitempty"`
+	Value    *float64       `json:"value,omitempty"`
+	Children []*OrderReason `json:"children,omitempty"`
 }
 
 // Position represents a trading position in the market.
@@ -106,6 +153,15 @@ type Position interface {
 
 	// Backtesting only: position can get canceled if there is gaps in data
 	Canceled() bool
+
+	// Price at which the position is stopped, moved by SetStopLoss
+	StopLoss() float64
+
+	// Price at which the position was stopped when opened, its initial risk
+	InitialStopLoss() float64
+
+	// Why the position was closed (e.g., stop loss, take profit, or the reason given to ClosePosition)
+	CloseReason() string
 }
 
 // Broker is an interface that defines the methods required to interact with a trading broker.
@@ -121,6 +177,7 @@ type Broker interface {
 	GetCapital() float64
 
 	// Register a callback to receive market data for a specific timeframe.
+	// When candles of several timeframes close at the same time, the higher timeframes are called first.
 	RegisterMarketDataCallback(timeframe Timeframe, callback func(candle Candle))
 
 	// Get the current time.
@@ -129,6 +186,12 @@ type Broker interface {
 
 	// Place an order to enter a position in the market.
 	PlaceOrder(order *Order) (Position, error)
+
+	// Close an open position at the current price, before its stop loss or take profit.
+	ClosePosition(position Position, reason string) error
+
+	// Move the stop loss of an open position, e.g., to trail the price.
+	SetStopLoss(position Position, stopLoss float64) error
 }
 
 // BacktestingBroker extends the Broker interface to include methods specific to backtesting scenarios.
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"trading-bot/brokers/backtesting"
+	"trading-bot/common"
+	"trading-bot/diagnostics"
+	"trading-bot/gridsearch"
+	"trading-bot/runner"
+	strategies "trading-bot/strategies/modular"
+	"trading-bot/studyfile"
+	"trading-bot/traders"
+	"trading-bot/traders/modular"
+)
+
+func main() {
+	studyName := flag.String("study", "", "only the strategies of this gridsearch study, ranked by its objective")
+	studyFile := flag.String("file", "", "study file of the gridsearch study, if it was not run on the breakout strategy")
+	instrument := flag.String("instrument", "EURUSD", "instrument of the runs")
+	monthRange := flag.String("months", "2023-01..2023-06", "months of the runs")
+	continuous := flag.Bool("continuous", false, "use runs over all months at once, instead of month by month")
+	objectiveName := flag.String("objective", string(runner.ObjectiveSharpe), "ranking objective without study")
+	splits := flag.Int("splits", 8, "number of blocks of months of the cross-validation, even")
+	top := flag.Int("top", 10, "number of top-ranked strategies to judge")
+	flag.Parse()
+
+	from, to, err := common.ParseMonthRange(*monthRange)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	months := common.Months(from, to)
+
+	objective, err := runner.ParseObjective(*objectiveName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	db, err := runner.OpenDatabase()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	filter := &runner.RunFilter{
+		Instrument: *instrument,
+		From:       from.String(),
+		To:         to.String(),
+
+		// Results of older engine versions may be wrong
+		EngineVersion: backtesting.EngineVersion,
+
+		// A single run per month, so that the months of overlapping runs are not aggregated together
+		RunRanges: runner.RunRanges(from, to, *continuous),
+	}
+
+	runs, err := db.FindRuns(filter)
+	if err != nil {
+		panic(err)
+	}
+
+	var selected map[string]bool // Identities of the strategies of the study
+	if *studyName != "" {
+		study, err := db.FindStudy(*studyName)
+		if err != nil {
+			panic(err)
+		}
+		if study == nil {
+			fmt.Fprintf(os.Stderr, "unknown study: %s\n", *studyName)
+			os.Exit(2)
+		}
+
+		objective = study.Objective
+		selected, err = studyStrategies(study, *studyFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// Strategies with results in every month, a column each per settings
+	var stats []*runner.StrategyStats
+	for _, s := range runner.AggregateByStrategy(runs) {
+		if s.Months == len(months) && (selected == nil || selected[s.Strategy]) {
+			stats = append(stats, s)
+		}
+	}
+
+	if len(stats) < 2 {
+		fmt.Printf("Not enough strategies with results over %s: %d\n", common.FormatMonthRange(from, to), len(stats))
+		return
+	}
+
+	// PnL of each strategy and settings by month
+	results := make(map[strategySettings]map[string]float64)
+	for _, r := range runs {
+		key := strategySettings{r.Strategy, r.Settings}
+		if results[key] == nil {
+			results[key] = make(map[string]float64)
+		}
+		results[key][r.TimeRange] += r.NetPnL
+	}
+
+	pnl := monthlyPnL(results, stats, months)
+
+	pbo, err := diagnostics.PBO(pnl, *splits)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("\n🔬 Overfitting diagnostics (%d strategies, %d months)\n", len(stats), len(months))
+	fmt.Printf("==========================================\n\n")
+	fmt.Printf("Probability of Backtest Overfitting: %.1f%% (%d partitions of %d blocks)\n", pbo.PBO*100, pbo.Combinations, pbo.Splits)
+	fmt.Printf("Median logit: %.3f, probability of out-of-sample loss: %.1f%%\n", pbo.MedianLogit, pbo.ProbabilityLoss*100)
+	if pbo.PBO > diagnostics.OverfitPBO {
+		fmt.Printf("❌ The selection of the best strategy likely overfits: it is below the median out-of-sample more often than not\n")
+	}
+
+	trialsVariance := diagnostics.SharpeVariance(pnl)
+
+	// Columns follow stats, keep them before ranking
+	columns := make(map[*runner.StrategyStats]int, len(stats))
+	for i, s := range stats {
+		columns[s] = i
+	}
+
+	runner.RankStrategies(stats, objective)
+
+	fmt.Printf("\n%4s │ %-8s │ %-8s │ %10s │ %8s │ %8s │ %8s │ %10s │ %s\n",
+		"#", "ID", "Settings", "Objective", "Sharpe", "Max SR", "DSR", "Net P&L", "Verdict")
+
+	for i, s := range stats[:min(*top, len(stats))] {
+		column := make([]float64, len(months))
+		for m := range months {
+			column[m] = pnl[m][columns[s]]
+		}
+
+		result, err := diagnostics.DeflatedSharpe(column, len(stats), trialsVariance)
+		if err != nil {
+			panic(err)
+		}
+
+		verdict := diagnostics.Judge(result.DeflatedSharpe, pbo.PBO)
+
+		fmt.Printf("%4d │ %-8s │ %-8s │ %10.3f │ %8.3f │ %8.3f │ %7.1f%% │ %10.2f │ %s %s\n",
+			i+1, s.StrategyID, s.SettingsID, objective.Value(s), result.Sharpe, result.ExpectedMax, result.DeflatedSharpe*100, s.NetPnL,
+			verdictIcon(verdict), verdict)
+	}
+
+	fmt.Printf("\nDSR is the probability that the Sharpe ratio beats the expected maximum (Max SR) of %d trials without edge\n", len(stats))
+}
+
+// studyStrategies returns the identities of the strategies of the done trials of a gridsearch study,
+// declared by a study file or else run on the breakout strategy.
+func studyStrategies(study *runner.Study, path string) (map[string]bool, error) {
+	space, build, err := studySpace(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trials, err := study.Trials(space)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trials: %w", err)
+	}
+
+	identities := make(map[string]bool, len(trials))
+	for _, trial := range trials {
+		if trial.Status != gridsearch.TrialStatusDone {
+			continue
+		}
+
+		builder, err := build(trial.Combo)
+		if err != nil {
+			return nil, err
+		}
+		identities[traders.NewModularStrategy(builder).Identity()] = true
+	}
+
+	return identities, nil
+}
+
+// studySpace returns the parameter space and the trader builder of a study file, or else of the breakout strategy.
+func studySpace(path string) (*gridsearch.ParameterSpace, func(gridsearch.Combo) (modular.Builder, error), error) {
+	if path == "" {
+		space, err := strategies.BreakoutSpace()
+		return space, strategies.BreakoutGSBuilder, err
+	}
+
+	study, err := studyfile.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return study.Space, study.Build, nil
+}
+
+// strategySettings identifies the runs of a strategy under the same settings, aggregated in a column.
+type strategySettings struct {
+	strategy, settings string
+}
+
+// monthlyPnL returns the PnL of each month (rows) of each strategy (columns).
+func monthlyPnL(results map[strategySettings]map[string]float64, stats []*runner.StrategyStats, months []common.Month) [][]float64 {
+	pnl := make([][]float64, len(months))
+	for m, month := range months {
+		pnl[m] = make([]float64, len(stats))
+		for i, s := range stats {
+			pnl[m][i] = results[strategySettings{s.Strategy, s.Settings}][month.String()]
+		}
+	}
+	return pnl
+}
+
+func verdictIcon(verdict diagnostics.Verdict) string {
+	switch verdict {
+	case diagnostics.VerdictSignificant:
+		return "✅"
+	case diagnostics.VerdictOverfit:
+		return "❌"
+	default:
+		return "⚠️ "
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"time"
+	"trading-bot/common"
+	"trading-bot/evolution"
+	"trading-bot/runner"
+	"trading-bot/traders"
+	"trading-bot/traders/modular"
+	"trading-bot/traders/modular/conditions"
+	"trading-bot/traders/modular/indicators"
+	"trading-bot/traders/modular/ordercomputer"
+)
+
+func main() {
+	config := evolution.DefaultConfig()
+
+	flag.Int64Var(&config.Seed, "seed", config.Seed, "seed of the evolution, the same seed evolves the same strategies")
+	flag.IntVar(&config.Population, "population", config.Population, "number of strategies per generation")
+	flag.IntVar(&config.Generations, "generations", config.Generations, "number of generations")
+	flag.IntVar(&config.MaxDepth, "max-depth", config.MaxDepth, "maximum depth of the trigger trees, 1 for a single condition")
+	flag.Float64Var(&config.ParsimonyPenalty, "parsimony", config.ParsimonyPenalty, "penalty per node of the triggers, subtracted from the objective")
+	objectiveName := flag.String("objective", string(runner.ObjectiveSharpe), "objective maximized by the evolution")
+	continuous := flag.Bool("continuous", false, "run each strategy over all months at once, instead of month by month")
+	serveAddr := flag.String("serve", "", "do not run locally, serve the jobs to workers on this local HTTP address (e.g., localhost:8082)")
+	flag.Parse()
+
+	objective, err := runner.ParseObjective(*objectiveName)
+	if err != nil {
+		panic(err)
+	}
+
+	// Ctrl-C stops the evolution once the running backtests are finished
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	instrument := "EURUSD"
+	from, to := common.NewMonth(2023, 1), common.NewMonth(2023, 6)
+	months := common.Months(from, to)
+
+	settings := runner.DefaultSettings()
+
+	r, err := newRunner(ctx, *serveAddr)
+	if err != nil {
+		panic(err)
+	}
+	defer r.Close()
+
+	// Only the runs submitted by the evolution, and not those of overlapping runs or of other settings
+	filter := runner.RunFilter{
+		Instrument: instrument,
+		From:       from.String(),
+		To:         to.String(),
+		DataSource: string(settings.DataSource),
+		Settings:   settings.Identity(),
+		RunRanges:  runner.RunRanges(from, to, *continuous),
+	}
+
+	// evaluate runs the strategies of the genomes in parallel.
+	// A genome fails if some of its months have no results.
+	evaluate := func(ctx context.Context, genomes []*evolution.Genome) ([]float64, error) {
+		batch := r.NewBatch()
+		strategies := make([]traders.Strategy, len(genomes))
+
+		for i, genome := range genomes {
+			builder := template()
+			if err := genome.SetTriggers(builder); err != nil {
+				return nil, err
+			}
+			strategies[i] = traders.NewModularStrategy(builder)
+
+			var err error
+			if *continuous {
+				err = batch.SubmitRange(instrument, from, to, strategies[i], settings)
+			} else {
+				for _, month := range months {
+					if err = batch.SubmitRun(instrument, month, strategies[i], settings); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := batch.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		values := make([]float64, len(genomes))
+		for i, strategy := range strategies {
+			stats, err := r.StrategyStats(strategy, filter)
+			if err != nil {
+				return nil, err
+			}
+
+			if stats == nil || stats.Months != len(months) {
+				values[i] = math.Inf(-1)
+			} else {
+				values[i] = objective.Value(stats)
+			}
+		}
+
+		return values, nil
+	}
+
+	population, err := evolution.Evolve(ctx, config, evaluate)
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("Canceled, evaluated strategies are reused by the next run with the same seed\n")
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	for rank, individual := range population[:min(5, len(population))] {
+		if individual.IsFailed() {
+			break
+		}
+
+		builder := template()
+		if err := individual.Genome.SetTriggers(builder); err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("🏆 #%d: fitness %.4f, %s %.4f, %d nodes\n%s\n%s\n\n",
+			rank+1, individual.Fitness, objective, individual.Objective, individual.Genome.Size(),
+			modular.Format(builder), modular.ToJSON(builder))
+	}
+}
+
+func newRunner(ctx context.Context, serveAddr string) (*runner.Runner, error) {
+	if serveAddr != "" {
+		return runner.NewDistributedRunner(ctx, serveAddr)
+	}
+	return runner.NewRunner(ctx)
+}
+
+// template returns the trader of the evolved triggers, whose filter and risk management are fixed.
+func template() modular.Builder {
+	builder := modular.NewBuilder()
+	builder.SetHistorySize(250)
+
+	builder.Strategy().SetFilter(conditions.And(
+		conditions.HistoryUsable(),
+		conditions.NoOpenPositions(),
+
+		conditions.Weekday(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday),
+		conditions.ExcludeUKHolidays(),
+		conditions.ExcludeUSHolidays(),
+		conditions.Session(common.LondonSession),
+		conditions.Session(common.NYSession),
+	))
+
+	builder.RiskManager().SetStopLoss(
+		ordercomputer.StopLossATR(indicators.ATR(14), 1.0),
+	).SetTakeProfit(
+		ordercomputer.TakeProfitRatio(2.0),
+	)
+
+	builder.CapitalAllocator().SetAllocator(
+		ordercomputer.CapitalFixed(10),
+	)
+
+	return builder
+}
@@ -1,64 +1,311 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
 	"trading-bot/common"
 	"trading-bot/gridsearch"
 	"trading-bot/runner"
-	"trading-bot/strategies"
-	"trading-bot/traders/modular"
-	"trading-bot/traders/modular/indicators"
-	"trading-bot/traders/modular/ordercomputer"
+	strategies "trading-bot/strategies/modular"
+	"trading-bot/studyfile"
+	"trading-bot/walkforward"
 )
 
 func main() {
-	instrument := "EURUSD"
+	progressAddr := flag.String("progress-addr", "", "serve progress on this local HTTP address (e.g., localhost:8081)")
+	resume := flag.Bool("resume", false, "only run again the pending and failed jobs of previous invocations")
+	failures := flag.Bool("failures", false, "print the failed jobs grouped by error, and exit")
+	datasetBudget := flag.Int64("dataset-budget", runner.DefaultDatasetBudget>>20, "memory budget of the dataset cache, in MiB")
+	serveAddr := flag.String("serve", "", "do not run locally, serve the jobs to workers on this local HTTP address (e.g., localhost:8082)")
+	studyFile := flag.String("file", "", "run the study declared in this JSON file (e.g., studies/breakout.json), instead of the breakout strategy configured by the next flags")
+	paretoPath := flag.String("pareto-out", "output/pareto.json", "file of the exported Pareto front")
 
-	months := []common.Month{
-		common.NewMonth(2023, 1),
-		common.NewMonth(2023, 2),
-		common.NewMonth(2023, 3),
-		common.NewMonth(2023, 4),
-		common.NewMonth(2023, 5),
-		common.NewMonth(2023, 6),
+	continuous := flag.Bool("continuous", false, "run each strategy over all months at once, instead of month by month")
+	warmUp := flag.Duration("warm-up", 0, "duration of data before each run used to fill the history, without trading (e.g., 72h)")
+	samplerName := flag.String("sampler", string(gridsearch.SamplerGrid), "how to choose the strategies: grid, random, lhs or sobol")
+	budget := flag.Int("budget", 100, "number of strategies to sample, ignored by the grid sampler")
+	seed := flag.Int64("seed", 1, "seed of the sampler or optimizer, the same seed samples the same strategies")
+	studyName := flag.String("study", "", "optimize with TPE in this study instead of sampling, resumed if it exists")
+	trials := flag.Int("trials", 100, "number of strategies evaluated by the study")
+	batchSize := flag.Int("batch", runtime.NumCPU(), "number of strategies proposed at once by the study, run in parallel")
+	objectiveName := flag.String("objective", string(runner.ObjectiveSharpe), "objective maximized by the study, or by each walk-forward window")
+	monthRange := flag.String("months", "2023-01..2023-06", "months to run the strategies on")
+	walkForward := flag.String("walk-forward", "", "optimize on in-sample windows and trade the best strategy on the next months: rolling or anchored")
+	inSample := flag.Int("in-sample", 6, "number of months of the walk-forward in-sample windows")
+	outOfSample := flag.Int("out-of-sample", 1, "number of months of the walk-forward out-of-sample windows")
+	objectives := flag.String("objectives", "", "export the Pareto front of the sampled strategies over these objectives, with optional weights and minimums (e.g., expectancy:2,maxDrawdown,trades>=30)")
+	flag.Parse()
+
+	var study *studyfile.Study
+	var err error
+
+	if *studyFile != "" {
+		study, err = studyfile.Load(*studyFile)
+	} else {
+		study, err = flagStudy(&studyFlags{
+			continuous:  *continuous,
+			warmUp:      *warmUp,
+			sampler:     *samplerName,
+			budget:      *budget,
+			seed:        *seed,
+			study:       *studyName,
+			trials:      *trials,
+			batch:       *batchSize,
+			objective:   *objectiveName,
+			months:      *monthRange,
+			walkForward: *walkForward,
+			inSample:    *inSample,
+			outOfSample: *outOfSample,
+			objectives:  *objectives,
+		})
+	}
+	if err != nil {
+		panic(err)
 	}
 
-	runner, err := runner.NewRunner()
+	// Ctrl-C finishes running backtests, queued ones are run by the next invocation
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	months := study.Months()
+
+	settings := runner.DefaultSettings()
+	settings.WarmUp = study.WarmUp
+
+	runner, err := newRunner(ctx, *serveAddr)
 	if err != nil {
 		panic(err)
 	}
 	defer runner.Close()
 
-	combos := strategies.BreakoutSpace.GenerateCombinations()
+	runner.SetDatasetBudget(*datasetBudget << 20)
 
-	fmt.Printf("Combined %d strategies\n", len(combos))
+	if *failures {
+		printFailures(runner)
+		return
+	}
 
-	for _, combo := range combos {
-		for _, month := range months {
-			strategy := buildStrategy(combo)
-			if err := runner.SubmitRun(instrument, month, strategy); err != nil {
-				panic(err)
+	if *progressAddr != "" {
+		runner.ServeProgress(*progressAddr)
+	}
+
+	if *resume {
+		count, err := runner.ResumeJobs()
+		if errors.Is(err, context.Canceled) {
+			fmt.Printf("Canceled, stop submitting\n")
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Resumed %d jobs\n", count)
+		return
+	}
+
+	submit := func(s submitter, combo gridsearch.Combo) error {
+		for _, instrument := range study.Instruments {
+			if study.Continuous {
+				strategy, err := study.Strategy(combo)
+				if err != nil {
+					return err
+				}
+				if err := s.SubmitRange(instrument, study.From, study.To, strategy, settings); err != nil {
+					return err
+				}
+				continue
+			}
+
+			for _, month := range months {
+				strategy, err := study.Strategy(combo)
+				if err != nil {
+					return err
+				}
+				if err := s.SubmitRun(instrument, month, strategy, settings); err != nil {
+					return err
+				}
 			}
 		}
+		return nil
+	}
+
+	// A complete evaluation has a run per month and instrument
+	runs := len(months) * len(study.Instruments)
+
+	if study.Optimizer != "" {
+		s, err := runner.OpenStudy(study.Name, study.Optimizer, study.Objective, study.Seed)
+		if err != nil {
+			panic(err)
+		}
+
+		o := &optimization{
+			runner:    runner,
+			study:     s,
+			space:     study.Space,
+			trials:    study.Trials,
+			batchSize: study.Batch,
+			submit:    submit,
+			strategy:  study.Strategy,
+			filter:    runFilter(study, settings),
+			runs:      runs,
+		}
+
+		err = o.run(ctx)
+		if errors.Is(err, context.Canceled) {
+			fmt.Printf("Canceled, resume with the same study\n")
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	combos, err := study.Space.Sample(study.Sampler, study.Budget, study.Seed)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Sampled %d of %d strategies (%s)\n", len(combos), study.Space.Size(), study.Sampler)
+
+	if study.WalkForward != nil {
+		windows, err := walkforward.Windows(study.From, study.To, study.WalkForward.InSample, study.WalkForward.OutOfSample, study.WalkForward.Mode)
+		if err != nil {
+			panic(err)
+		}
+
+		config := &walkforward.Config{
+			Instrument: study.Instruments[0],
+			Windows:    windows,
+			Objective:  study.Objective,
+			Continuous: study.Continuous,
+			Settings:   settings,
+		}
+
+		report, err := walkforward.Run(ctx, runner, config, combos, study.Strategy)
+		if errors.Is(err, context.Canceled) {
+			fmt.Printf("Canceled, results are reused by the next invocation\n")
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+
+		printWalkForward(report, study.Objective)
+		return
+	}
+
+	if study.Objectives != nil {
+		err := paretoFront(ctx, runner, study, combos, submit, runFilter(study, settings), runs, *paretoPath)
+		if errors.Is(err, context.Canceled) {
+			fmt.Printf("Canceled, results are reused by the next invocation\n")
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	for _, combo := range combos {
+		err := submit(runner, combo)
+		if errors.Is(err, context.Canceled) {
+			fmt.Printf("Canceled, stop submitting\n")
+			return
+		}
+		if err != nil {
+			panic(err)
+		}
 	}
 }
 
-func buildStrategy(combo gridsearch.Combo) modular.Builder {
-	builder := modular.NewBuilder()
-	builder.SetHistorySize(250)
+// studyFlags configure the breakout study without study file.
+type studyFlags struct {
+	continuous            bool
+	warmUp                time.Duration
+	sampler               string
+	budget                int
+	seed                  int64
+	study                 string
+	trials, batch         int
+	objective, months     string
+	walkForward           string
+	inSample, outOfSample int
+	objectives            string
+}
 
-	strategies.BreakoutGS(builder.Strategy(), combo)
+func flagStudy(flags *studyFlags) (*studyfile.Study, error) {
+	study := &studyfile.Study{
+		Name:        flags.study,
+		Instruments: []string{"EURUSD"},
+		Continuous:  flags.continuous,
+		WarmUp:      flags.warmUp,
+		Budget:      flags.budget,
+		Seed:        flags.seed,
+		Trials:      flags.trials,
+		Batch:       flags.batch,
+		Build:       strategies.BreakoutGSBuilder,
+	}
 
-	builder.RiskManager().SetStopLoss(
-		ordercomputer.StopLossATR(indicators.ATR(14), 1.0),
-		//ordercomputer.StopLossPipBuffer(3, 15),
-	).SetTakeProfit(
-		ordercomputer.TakeProfitRatio(2.0),
-	)
+	var err error
 
-	builder.CapitalAllocator().SetAllocator(
-		ordercomputer.CapitalFixed(10),
-	)
+	if study.Name != "" {
+		study.Optimizer = studyfile.OptimizerTPE
+	}
+	if study.From, study.To, err = common.ParseMonthRange(flags.months); err != nil {
+		return nil, err
+	}
+	if study.Sampler, err = gridsearch.ParseSampler(flags.sampler); err != nil {
+		return nil, err
+	}
+	if study.Objective, err = runner.ParseObjective(flags.objective); err != nil {
+		return nil, err
+	}
+	if flags.objectives != "" {
+		if study.Objectives, err = runner.ParseMultiObjective(flags.objectives); err != nil {
+			return nil, err
+		}
+	}
+	if flags.walkForward != "" {
+		study.WalkForward = &studyfile.WalkForward{InSample: flags.inSample, OutOfSample: flags.outOfSample}
+		if study.WalkForward.Mode, err = walkforward.ParseMode(flags.walkForward); err != nil {
+			return nil, err
+		}
+	}
+	if err := study.Validate(); err != nil {
+		return nil, err
+	}
+	if study.Space, err = strategies.BreakoutSpace(); err != nil {
+		return nil, err
+	}
+
+	return study, nil
+}
+
+func newRunner(ctx context.Context, serveAddr string) (*runner.Runner, error) {
+	if serveAddr != "" {
+		return runner.NewDistributedRunner(ctx, serveAddr)
+	}
+	return runner.NewRunner(ctx)
+}
 
-	return builder
+func printFailures(r *runner.Runner) {
+	groups, err := r.FailureReport()
+	if err != nil {
+		panic(err)
+	}
+
+	if len(groups) == 0 {
+		fmt.Printf("No failed jobs\n")
+		return
+	}
+
+	for _, group := range groups {
+		fmt.Printf("❌ %d jobs (max %d attempts, e.g., %s): %s\n", group.Count, group.MaxAttempts, group.Example, group.Error)
+	}
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"trading-bot/common"
+	"trading-bot/gridsearch"
+	"trading-bot/runner"
+	"trading-bot/studyfile"
+	"trading-bot/traders"
+)
+
+// submitter submits backtests, either a runner or a batch.
+type submitter interface {
+	SubmitRun(instrument string, month common.Month, strategy traders.Strategy, settings *runner.Settings) error
+	SubmitRange(instrument string, from, to common.Month, strategy traders.Strategy, settings *runner.Settings) error
+}
+
+// optimization evaluates the strategies proposed by TPE, batch after batch, until the study has enough trials.
+type optimization struct {
+	runner    *runner.Runner
+	study     *runner.Study
+	space     *gridsearch.ParameterSpace
+	trials    int // Number of trials of the study
+	batchSize int
+	submit    func(submitter, gridsearch.Combo) error
+	strategy  func(gridsearch.Combo) (traders.Strategy, error)
+	filter    runner.RunFilter // Runs submitted by the study
+	runs      int              // Number of runs of a complete evaluation
+}
+
+// runFilter selects the runs submitted by the study, and not those of overlapping runs or of other settings.
+func runFilter(study *studyfile.Study, settings *runner.Settings) runner.RunFilter {
+	return runner.RunFilter{
+		Instruments: study.Instruments,
+		From:        study.From.String(),
+		To:          study.To.String(),
+		DataSource:  string(settings.DataSource),
+		Settings:    settings.Identity(),
+		RunRanges:   runner.RunRanges(study.From, study.To, study.Continuous),
+	}
+}
+
+func (o *optimization) run(ctx context.Context) error {
+	tpe := gridsearch.NewTPE(o.space, o.study.Seed)
+
+	trials, err := o.study.Trials(o.space)
+	if err != nil {
+		return fmt.Errorf("failed to load trials: %w", err)
+	}
+
+	fmt.Printf("Study %s: %d of %d trials (%s)\n", o.study.Name, len(trials), o.trials, o.study.Objective)
+
+	for {
+		// Trials left pending by a previous invocation are evaluated first
+		pending := []*gridsearch.Trial{}
+		for _, trial := range trials {
+			if trial.Status == gridsearch.TrialStatusPending {
+				pending = append(pending, trial)
+			}
+		}
+
+		if len(pending) == 0 {
+			if len(trials) >= o.trials {
+				break
+			}
+
+			combos, err := tpe.Propose(trials, min(o.batchSize, o.trials-len(trials)))
+			if err != nil {
+				return fmt.Errorf("failed to propose strategies: %w", err)
+			}
+			if len(combos) == 0 {
+				fmt.Printf("Parameter space exhausted\n")
+				break
+			}
+
+			for _, combo := range combos {
+				trial := &gridsearch.Trial{Index: len(trials), Combo: combo, Status: gridsearch.TrialStatusPending}
+				if err := o.study.SaveTrial(trial); err != nil {
+					return fmt.Errorf("failed to save trial: %w", err)
+				}
+				trials = append(trials, trial)
+				pending = append(pending, trial)
+			}
+		}
+
+		if err := o.evaluate(ctx, pending); err != nil {
+			return err
+		}
+
+		best := bestTrial(trials)
+		for _, trial := range pending {
+			if trial.Status == gridsearch.TrialStatusFailed {
+				fmt.Printf("❌ Trial %d failed: %s\n", trial.Index, trial.Combo.ID())
+			} else {
+				// A done trial makes best not nil
+				fmt.Printf("🔎 Trial %d: %.4f (best %.4f, trial %d)\n", trial.Index, trial.Value, best.Value, best.Index)
+			}
+		}
+	}
+
+	if best := bestTrial(trials); best != nil {
+		fmt.Printf("🏆 Best trial %d: %.4f %v\n", best.Index, best.Value, best.Combo)
+	}
+
+	return nil
+}
+
+// evaluate runs the strategies of the trials in parallel, and saves their objective.
+// A trial fails if some of its months have no results.
+func (o *optimization) evaluate(ctx context.Context, trials []*gridsearch.Trial) error {
+	batch := o.runner.NewBatch()
+
+	for _, trial := range trials {
+		if err := o.submit(batch, trial.Combo); err != nil {
+			return err
+		}
+	}
+
+	if err := batch.Wait(ctx); err != nil {
+		return err
+	}
+
+	for _, trial := range trials {
+		strategy, err := o.strategy(trial.Combo)
+		if err != nil {
+			return err
+		}
+
+		stats, err := o.runner.StrategyStats(strategy, o.filter)
+		if err != nil {
+			return err
+		}
+
+		if stats == nil || stats.Months != o.runs {
+			trial.Status = gridsearch.TrialStatusFailed
+		} else {
+			trial.Status = gridsearch.TrialStatusDone
+			trial.Value = o.study.Objective.Value(stats)
+		}
+
+		if err := o.study.SaveTrial(trial); err != nil {
+			return fmt.Errorf("failed to save trial: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func bestTrial(trials []*gridsearch.Trial) *gridsearch.Trial {
+	var best *gridsearch.Trial
+	for _, trial := range trials {
+		if trial.Status == gridsearch.TrialStatusDone && (best == nil || trial.Value > best.Value) {
+			best = trial
+		}
+	}
+	return best
+}
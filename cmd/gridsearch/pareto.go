@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"trading-bot/gridsearch"
+	"trading-bot/runner"
+	"trading-bot/studyfile"
+)
+
+// paretoStrategy is a strategy of the exported Pareto front.
+type paretoStrategy struct {
+	Rank       int                `json:"rank"` // By weighted score
+	Score      float64            `json:"score"`
+	Objectives map[string]float64 `json:"objectives"`
+	Combo      gridsearch.Combo   `json:"combo"`
+	Stats      *paretoStats       `json:"stats"`
+	Strategy   json.RawMessage    `json:"strategy"` // JSON of the modular trader, loadable by modular.FromJSON
+}
+
+// paretoStats are the stats of a strategy, without its description already exported as JSON.
+type paretoStats struct {
+	StrategyID          string  `json:"strategyId"`
+	Months              int     `json:"months"`
+	TotalTrades         int     `json:"totalTrades"`
+	NetPnL              float64 `json:"netPnL"`
+	WinRate             float64 `json:"winRate"`
+	ExpectedValueR      float64 `json:"expectedValueR"`
+	MaxDrawdownPct      float64 `json:"maxDrawdownPct"`
+	ProfitableMonthsPct float64 `json:"profitableMonthsPct"`
+}
+
+// paretoFront runs the combinations in parallel, and exports the Pareto front of their results.
+// filter selects the runs submitted by the study (see runFilter), combinations without results over all of them are not candidates.
+func paretoFront(ctx context.Context, r *runner.Runner, study *studyfile.Study, combos []gridsearch.Combo,
+	submit func(submitter, gridsearch.Combo) error, filter runner.RunFilter, runs int, path string) error {
+
+	batch := r.NewBatch()
+	for _, combo := range combos {
+		if err := submit(batch, combo); err != nil {
+			return err
+		}
+	}
+	if err := batch.Wait(ctx); err != nil {
+		return err
+	}
+
+	var candidates []*runner.StrategyStats
+	byStrategy := make(map[string]gridsearch.Combo, len(combos))
+
+	for _, combo := range combos {
+		strategy, err := study.Strategy(combo)
+		if err != nil {
+			return err
+		}
+
+		stats, err := r.StrategyStats(strategy, filter)
+		if err != nil {
+			return err
+		}
+		if stats == nil || stats.Months != runs {
+			fmt.Printf("❌ No results over all months: %s\n", combo.ID())
+			continue
+		}
+
+		candidates = append(candidates, stats)
+		byStrategy[stats.Strategy] = combo
+	}
+
+	mo := study.Objectives
+	front := mo.ParetoFront(candidates)
+
+	exported := make([]*paretoStrategy, len(front))
+	for i, point := range front {
+		s := point.Stats
+
+		objectives := make(map[string]float64, len(mo.Criteria))
+		for j, c := range mo.Criteria {
+			objectives[string(c.Objective)] = point.Values[j]
+		}
+
+		exported[i] = &paretoStrategy{
+			Rank:       i + 1,
+			Score:      point.Score,
+			Objectives: objectives,
+			Combo:      byStrategy[s.Strategy],
+			Stats: &paretoStats{
+				StrategyID:          s.StrategyID,
+				Months:              s.Months,
+				TotalTrades:         s.TotalTrades,
+				NetPnL:              s.NetPnL,
+				WinRate:             s.WinRate,
+				ExpectedValueR:      s.ExpectedValueR,
+				MaxDrawdownPct:      s.MaxDrawdownPct,
+				ProfitableMonthsPct: s.ProfitableMonthsPct,
+			},
+			Strategy: json.RawMessage(s.Strategy),
+		}
+
+		fmt.Printf("🏆 #%d score %.3f %v %v\n", i+1, point.Score, objectives, exported[i].Combo)
+	}
+
+	fmt.Printf("Pareto front of %d strategies, out of %d candidates meeting %s\n", len(front), len(candidates), mo)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize Pareto front: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to export Pareto front: %w", err)
+	}
+
+	fmt.Printf("Pareto front exported to %s\n", path)
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"trading-bot/runner"
+	"trading-bot/walkforward"
+)
+
+func printWalkForward(report *walkforward.Report, objective runner.Objective) {
+	fmt.Printf("\n📅 Windows\n")
+	for _, window := range report.Windows {
+		if window.Best == nil {
+			fmt.Printf("❌ %s → %s: no strategy with results in-sample\n", window.InSampleRange(), window.OutOfSampleRange())
+			continue
+		}
+
+		fmt.Printf("%s → %s: %s %.4f in-sample", window.InSampleRange(), window.OutOfSampleRange(), objective, objective.Value(window.InSample))
+		if window.OutOfSample != nil {
+			fmt.Printf(", %.4f out-of-sample, PnL %.2f, efficiency %s", objective.Value(window.OutOfSample), window.OutOfSample.NetPnL, formatEfficiency(window.Efficiency))
+		}
+		fmt.Printf("\n    %v\n", window.Best)
+	}
+
+	fmt.Printf("\n📈 Out-of-sample equity\n")
+	for _, point := range report.Equity {
+		fmt.Printf("%s: %+10.2f → %12.2f\n", point.Month, point.PnL, point.Equity)
+	}
+
+	fmt.Printf("\n⚖️  Walk-forward efficiency: %s\n", formatEfficiency(report.Efficiency))
+
+	fmt.Printf("\n🧭 Parameter stability\n")
+	for _, s := range report.Stability {
+		fmt.Printf("%-16s %v in %.0f%% of windows, %d distinct values, %d changes\n", s.Parameter, s.Mode, s.ModeShare, s.Distinct, s.Changes)
+	}
+}
+
+func formatEfficiency(efficiency float64) string {
+	if math.IsNaN(efficiency) {
+		return "n/a (in-sample not profitable)"
+	}
+	return fmt.Sprintf("%.0f%%", efficiency*100)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"trading-bot/brokers/backtesting"
+	"trading-bot/common"
+	"trading-bot/runner"
+)
+
+func main() {
+	instrument := flag.String("instrument", "", "only runs on this instrument (e.g., EURUSD)")
+	kind := flag.String("kind", "", "only strategies of this kind (modular, expression)")
+	from := flag.String("from", "", "first month, inclusive (e.g., 2023-01)")
+	to := flag.String("to", "", "last month, inclusive (e.g., 2023-12)")
+	continuous := flag.Bool("continuous", false, "use runs from -from to -to at once, instead of month by month")
+	minTrades := flag.Int("min-trades", 0, "minimum number of trades of a strategy")
+	objectiveName := flag.String("objective", string(runner.ObjectiveNetPnL), "ranking objective: "+objectiveNames())
+	top := flag.Int("top", 20, "number of strategies to show (0 for all)")
+	asJSON := flag.Bool("json", false, "output as JSON")
+	flag.Parse()
+
+	objective, err := runner.ParseObjective(*objectiveName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	db, err := runner.OpenDatabase()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	filter := &runner.RunFilter{
+		Instrument:   *instrument,
+		From:         *from,
+		To:           *to,
+		StrategyKind: *kind,
+
+		// Results of older engine versions may be wrong
+		EngineVersion: backtesting.EngineVersion,
+	}
+
+	// A single run per month, so that the months of overlapping runs are not aggregated together
+	if *continuous {
+		first, last, err := common.ParseMonthRange(*from + ".." + *to)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "-continuous needs -from and -to: %v\n", err)
+			os.Exit(2)
+		}
+		filter.RunRanges = runner.RunRanges(first, last, true)
+	} else {
+		filter.SingleMonths = true
+	}
+
+	runs, err := db.FindRuns(filter)
+	if err != nil {
+		panic(err)
+	}
+
+	stats := make([]*runner.StrategyStats, 0)
+	for _, s := range runner.AggregateByStrategy(runs) {
+		if s.TotalTrades >= *minTrades {
+			stats = append(stats, s)
+		}
+	}
+
+	runner.RankStrategies(stats, objective)
+
+	if *top > 0 && len(stats) > *top {
+		stats = stats[:*top]
+	}
+
+	if *asJSON {
+		printJSON(stats)
+	} else {
+		printTable(stats, objective, len(runs))
+	}
+}
+
+func objectiveNames() string {
+	names := make([]string, 0, len(runner.Objectives))
+	for _, objective := range runner.Objectives {
+		names = append(names, string(objective))
+	}
+	return strings.Join(names, ", ")
+}
+
+func printJSON(stats []*runner.StrategyStats) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(stats); err != nil {
+		panic(err)
+	}
+}
+
+func printTable(stats []*runner.StrategyStats, objective runner.Objective, runCount int) {
+	fmt.Printf("\n🏆 Strategy Leaderboard (by %s, %d runs)\n", objective, runCount)
+	fmt.Printf("==========================================\n\n")
+
+	fmt.Printf("%4s │ %-8s │ %-8s │ %-10s │ %6s │ %6s │ %12s │ %8s │ %8s │ %10s │ %12s │ %12s │ %12s │ %10s\n",
+		"#", "ID", "Settings", "Kind", "Months", "Trades", "Net P&L", "Win Rate", "Exp. R", "Prof. Mon.", "Worst Month", "Mean Month", "Stdev Month", "Objective")
+
+	for i, s := range stats {
+		var color string
+		if s.NetPnL > 0 {
+			color = "\033[32m" // Green
+		} else {
+			color = "\033[31m" // Red
+		}
+
+		fmt.Printf("%4d │ %-8s │ %-8s │ %-10s │ %6d │ %6d │ %s%12.2f\033[0m │ %7.1f%% │ %8.3f │ %9.1f%% │ %12.2f │ %12.2f │ %12.2f │ %10.3f\n",
+			i+1, s.StrategyID, s.SettingsID, s.Kind, s.Months, s.TotalTrades,
+			color, s.NetPnL,
+			s.WinRate, s.ExpectedValueR, s.ProfitableMonthsPct,
+			s.WorstMonthPnL, s.MeanMonthlyPnL, s.StdevMonthlyPnL,
+			objective.Value(s),
+		)
+	}
+
+	fmt.Printf("\n")
+}
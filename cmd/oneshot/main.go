@@ -3,9 +3,11 @@ package main
 import (
 	"fmt"
 	"time"
+	"trading-bot/benchmark"
 	"trading-bot/brokers"
 	"trading-bot/brokers/backtesting"
 	"trading-bot/common"
+	"trading-bot/metrics"
 	"trading-bot/strategies/expression/rangebreakout"
 	"trading-bot/traders"
 )
@@ -78,11 +80,47 @@ func main() {
 		panic(err)
 	}
 
+	printSignificance(allTrades)
+
+	baselines, err := benchmark.RunAll(brokerConfig, dataset, allTrades, 1)
+	if err != nil {
+		panic(err)
+	}
+
+	printBenchmarkComparison(benchmark.Summarize(allTrades), baselines)
+
 	if len(allTrades) < 50 {
 		printTradeDetails(allTrades)
 	}
 }
 
+func printSignificance(trades []*backtesting.Trade) {
+	rMultiples := make([]float64, 0, len(trades))
+	wins := make([]bool, 0, len(trades))
+	for _, trade := range trades {
+		rMultiples = append(rMultiples, trade.RMultiple)
+		wins = append(wins, trade.PnL > 0)
+	}
+
+	significance := metrics.ComputeSignificance(rMultiples, wins)
+
+	fmt.Printf("🧪 Statistical Significance\n")
+	fmt.Printf("==========================\n")
+	fmt.Printf("t-statistic (mean R): %.3f\n", significance.TStat)
+	fmt.Printf("p-value: %.4f\n", significance.PValue)
+	fmt.Printf("Win Rate CI: [%.1f%%, %.1f%%]\n", significance.WinRateCI.Low, significance.WinRateCI.High)
+	fmt.Printf("Expectancy CI: [%.3fR, %.3fR]\n", significance.ExpectancyCI.Low, significance.ExpectancyCI.High)
+	fmt.Printf("Min Track Record Length: %.0f trades\n", significance.MinTrackRecordLength)
+
+	if significance.Significant {
+		fmt.Printf("✅ Result is statistically significant\n")
+	} else {
+		fmt.Printf("\033[33m⚠️  Result is not distinguishable from zero\033[0m\n")
+	}
+
+	fmt.Printf("\n")
+}
+
 func printMetricsSummary(monthlyMetrics map[common.Month]*backtesting.Metrics) {
 	fmt.Printf("\n📊 Trading Summary\n")
 	fmt.Printf("==================\n")
@@ -208,6 +246,35 @@ func printMetricsSummary(monthlyMetrics map[common.Month]*backtesting.Metrics) {
 	fmt.Printf("\n")
 }
 
+func printBenchmarkComparison(strategy benchmark.Summary, baselines []*benchmark.Result) {
+	fmt.Printf("🏁 Benchmark Comparison\n")
+	fmt.Printf("======================\n")
+
+	fmt.Printf("%-12s │ %7s │ %12s │ %8s │ %8s\n", "", "Trades", "Net P&L", "Win Rate", "Exp. R")
+	fmt.Printf("%-12s │ %7d │ %12.2f │ %7.1f%% │ %8.3f\n", "Strategy", strategy.TotalTrades, strategy.NetPnL, strategy.WinRate, strategy.ExpectedValueR)
+	for _, baseline := range baselines {
+		summary := baseline.Summary
+		fmt.Printf("%-12s │ %7d │ %12.2f │ %7.1f%% │ %8.3f\n", baseline.Name, summary.TotalTrades, summary.NetPnL, summary.WinRate, summary.ExpectedValueR)
+	}
+
+	fmt.Printf("\nStrategy relative to baselines:\n")
+	for _, baseline := range baselines {
+		comparison := benchmark.Compare(strategy, baseline)
+
+		var color string
+		if comparison.NetPnL > 0 {
+			color = "\033[32m" // Green
+		} else {
+			color = "\033[31m" // Red
+		}
+
+		fmt.Printf("vs %-12s: %sP&L %+.2f\033[0m, Win Rate %+.1f pts, Exp. R %+.3f\n",
+			comparison.Baseline, color, comparison.NetPnL, comparison.WinRate, comparison.ExpectedValueR)
+	}
+
+	fmt.Printf("\n")
+}
+
 func printTradeDetails(trades []*backtesting.Trade) {
 	fmt.Printf("\n📋 Individual Trade Details\n")
 	fmt.Printf("===========================\n\n")
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"trading-bot/brokers/backtesting"
+	"trading-bot/runner"
+)
+
+func main() {
+	purge := flag.Bool("purge", false, "delete the results produced by older engine versions")
+	flag.Parse()
+
+	db, err := runner.OpenDatabase()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	counts, err := db.CountRunsByEngineVersion()
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Current engine version: %d\n\n", backtesting.EngineVersion)
+	fmt.Printf("%-8s %10s %12s  %s\n", "Version", "Runs", "Trades", "Status")
+
+	outdated := 0
+	for _, c := range counts {
+		status := "✅ current"
+		if c.EngineVersion < backtesting.EngineVersion {
+			status = "⚠️  outdated"
+			outdated += c.Runs
+		} else if c.EngineVersion > backtesting.EngineVersion {
+			status = "❓ newer than this build"
+		}
+		fmt.Printf("%-8d %10d %12d  %s\n", c.EngineVersion, c.Runs, c.Trades, status)
+	}
+
+	if !*purge {
+		if outdated > 0 {
+			fmt.Printf("\n%d outdated runs, use -purge to delete them\n", outdated)
+		}
+		return
+	}
+
+	deleted, err := db.PurgeRuns(backtesting.EngineVersion)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("\n🗑️  Deleted %d outdated runs\n", deleted)
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+	"trading-bot/brokers/backtesting"
+	"trading-bot/common"
+	"trading-bot/gridsearch"
+	"trading-bot/runner"
+	strategies "trading-bot/strategies/modular"
+	"trading-bot/studyfile"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// studySpace returns the parameter space of a study file, or else of the breakout strategy.
+func studySpace(path string) (*gridsearch.ParameterSpace, error) {
+	if path == "" {
+		return strategies.BreakoutSpace()
+	}
+
+	study, err := studyfile.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return study.Space, nil
+}
+
+// sweepFlags configure the sweep of the breakout strategy without study file, as the gridsearch flags.
+type sweepFlags struct {
+	sampler    string
+	budget     int
+	seed       int64
+	months     string
+	continuous bool
+	warmUp     time.Duration
+	objective  string
+}
+
+// sweepStudy returns the sampled sweep of a study file, or else of the breakout strategy configured by flags.
+func sweepStudy(path string, flags *sweepFlags) (*studyfile.Study, error) {
+	if path != "" {
+		return studyfile.Load(path)
+	}
+
+	study := &studyfile.Study{
+		Instruments: []string{"EURUSD"},
+		Continuous:  flags.continuous,
+		WarmUp:      flags.warmUp,
+		Budget:      flags.budget,
+		Seed:        flags.seed,
+		Build:       strategies.BreakoutGSBuilder,
+	}
+
+	var err error
+	if study.From, study.To, err = common.ParseMonthRange(flags.months); err != nil {
+		return nil, err
+	}
+	if study.Sampler, err = gridsearch.ParseSampler(flags.sampler); err != nil {
+		return nil, err
+	}
+	if study.Objective, err = runner.ParseObjective(flags.objective); err != nil {
+		return nil, err
+	}
+	if study.Space, err = strategies.BreakoutSpace(); err != nil {
+		return nil, err
+	}
+	return study, nil
+}
+
+// sweepTrials samples the combinations of a sweep again, and evaluates them with their saved runs.
+// Combinations without results over every month and instrument are pending trials, left out of the charts.
+func sweepTrials(db *runner.Database, study *studyfile.Study) ([]*gridsearch.Trial, error) {
+	combos, err := study.Space.Sample(study.Sampler, study.Budget, study.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := runner.DefaultSettings()
+	settings.WarmUp = study.WarmUp
+
+	// Runs submitted by gridsearch, and not those of overlapping runs or of other settings
+	runs, err := db.FindRuns(&runner.RunFilter{
+		Instruments:   study.Instruments,
+		From:          study.From.String(),
+		To:            study.To.String(),
+		DataSource:    string(settings.DataSource),
+		Settings:      settings.Identity(),
+		RunRanges:     runner.RunRanges(study.From, study.To, study.Continuous),
+		EngineVersion: backtesting.EngineVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byStrategy := make(map[string]*runner.StrategyStats)
+	for _, stats := range runner.AggregateByStrategy(runs) {
+		byStrategy[stats.Strategy] = stats
+	}
+
+	complete := len(study.Months()) * len(study.Instruments)
+
+	trials := make([]*gridsearch.Trial, len(combos))
+	for i, combo := range combos {
+		trials[i] = &gridsearch.Trial{Index: i, Combo: combo, Status: gridsearch.TrialStatusPending}
+
+		strategy, err := study.Strategy(combo)
+		if err != nil {
+			return nil, err
+		}
+
+		stats := byStrategy[strategy.Identity()]
+		if stats != nil && stats.Months == complete {
+			trials[i].Status = gridsearch.TrialStatusDone
+			trials[i].Value = study.Objective.Value(stats)
+		}
+	}
+
+	return trials, nil
+}
+
+// analysis names the analysed trials and their objective, in the charts.
+type analysis struct {
+	Name      string
+	Objective runner.Objective
+}
+
+// isolatedPeakScore is the stability score below which a trial is an isolated peak: its neighbours are much worse.
+const isolatedPeakScore = -1.0
+
+func main() {
+	studyName := flag.String("study", "", "gridsearch TPE study to analyse, otherwise the sampled sweep of the study file or of the breakout strategy")
+	studyFile := flag.String("file", "", "study file of the gridsearch study or sweep, if it was not run on the breakout strategy")
+	samplerName := flag.String("sampler", string(gridsearch.SamplerGrid), "sampler of the breakout sweep without study file: grid, random, lhs or sobol")
+	budget := flag.Int("budget", 100, "budget of the breakout sweep without study file, ignored by the grid sampler")
+	seed := flag.Int64("seed", 1, "seed of the breakout sweep without study file")
+	monthRange := flag.String("months", "2023-01..2023-06", "months of the breakout sweep without study file")
+	continuous := flag.Bool("continuous", false, "the breakout sweep without study file ran each strategy over all months at once")
+	warmUp := flag.Duration("warm-up", 0, "warm-up of the breakout sweep without study file")
+	objectiveName := flag.String("objective", string(runner.ObjectiveSharpe), "objective of the breakout sweep without study file")
+	x := flag.String("x", "", "parameter of the X axis, every pair of parameters if x and y are empty")
+	y := flag.String("y", "", "parameter of the Y axis")
+	aggregationName := flag.String("aggregate", string(gridsearch.AggregationMean), "how to marginalise the other parameters: mean or max")
+	top := flag.Int("top", 10, "number of best trials whose stability is shown")
+	out := flag.String("out", "output/sensitivity.html", "HTML file of the charts")
+	flag.Parse()
+
+	if (*x == "") != (*y == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	aggregation, err := gridsearch.ParseAggregation(*aggregationName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	db, err := runner.OpenDatabase()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	var space *gridsearch.ParameterSpace
+	var trials []*gridsearch.Trial
+	var study *analysis
+
+	if *studyName != "" {
+		s, err := db.FindStudy(*studyName)
+		if err != nil {
+			panic(err)
+		}
+		if s == nil {
+			fmt.Fprintf(os.Stderr, "unknown study: %s\n", *studyName)
+			os.Exit(2)
+		}
+
+		if space, err = studySpace(*studyFile); err != nil {
+			panic(err)
+		}
+		if trials, err = s.Trials(space); err != nil {
+			panic(err)
+		}
+		study = &analysis{Name: s.Name, Objective: s.Objective}
+	} else {
+		sweep, err := sweepStudy(*studyFile, &sweepFlags{
+			sampler:    *samplerName,
+			budget:     *budget,
+			seed:       *seed,
+			months:     *monthRange,
+			continuous: *continuous,
+			warmUp:     *warmUp,
+			objective:  *objectiveName,
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+
+		space = sweep.Space
+		if trials, err = sweepTrials(db, sweep); err != nil {
+			panic(err)
+		}
+		study = &analysis{
+			Name:      fmt.Sprintf("the %s sweep of %s", sweep.Sampler, common.FormatMonthRange(sweep.From, sweep.To)),
+			Objective: sweep.Objective,
+		}
+	}
+
+	pairs := [][2]string{{*x, *y}}
+	if *x == "" {
+		pairs = parameterPairs(space)
+	}
+
+	page := components.NewPage()
+	page.SetPageTitle(fmt.Sprintf("Sensitivity of %s", study.Name))
+
+	for _, pair := range pairs {
+		heatmap, err := space.Pivot(trials, pair[0], pair[1], aggregation)
+		if err != nil {
+			panic(err)
+		}
+		page.AddCharts(heatmapChart(heatmap, study, aggregation))
+	}
+
+	stabilities, err := space.LocalStability(trials)
+	if err != nil {
+		panic(err)
+	}
+
+	// Best trials first
+	slices.SortStableFunc(stabilities, func(a, b *gridsearch.Stability) int {
+		switch {
+		case a.Trial.Value > b.Trial.Value:
+			return -1
+		case a.Trial.Value < b.Trial.Value:
+			return 1
+		default:
+			return 0
+		}
+	})
+	stabilities = stabilities[:min(*top, len(stabilities))]
+
+	printStabilities(stabilities, study)
+	page.AddCharts(stabilityChart(stabilities, study))
+
+	if err := os.MkdirAll(filepath.Dir(*out), 0755); err != nil {
+		panic(err)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	if err := page.Render(f); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("\nCharts of %d pairs of parameters saved to %s\n", len(pairs), *out)
+}
+
+func parameterPairs(space *gridsearch.ParameterSpace) [][2]string {
+	names := space.Names()
+
+	var pairs [][2]string
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			pairs = append(pairs, [2]string{names[i], names[j]})
+		}
+	}
+	return pairs
+}
+
+func heatmapChart(heatmap *gridsearch.Heatmap, study *analysis, aggregation gridsearch.Aggregation) *charts.HeatMap {
+	var data []opts.HeatMapData
+	low, high := math.Inf(1), math.Inf(-1)
+
+	for yi, row := range heatmap.Values {
+		for xi, value := range row {
+			if math.IsNaN(value) {
+				data = append(data, opts.HeatMapData{Value: [3]interface{}{xi, yi, "-"}})
+				continue
+			}
+
+			data = append(data, opts.HeatMapData{Value: [3]interface{}{xi, yi, value}})
+			low, high = math.Min(low, value), math.Max(high, value)
+		}
+	}
+	if math.IsInf(low, 1) {
+		low, high = 0, 0
+	}
+
+	chart := charts.NewHeatMap()
+	chart.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{Width: "900px", Height: "500px"}),
+		charts.WithTitleOpts(opts.Title{
+			Title:    fmt.Sprintf("%s by %s and %s", study.Objective, heatmap.X.Name, heatmap.Y.Name),
+			Subtitle: fmt.Sprintf("%s over the other parameters", aggregation),
+		}),
+		charts.WithXAxisOpts(opts.XAxis{Name: heatmap.X.Name, Type: "category", Data: labels(heatmap.X.Values)}),
+		charts.WithYAxisOpts(opts.YAxis{Name: heatmap.Y.Name, Type: "category", Data: labels(heatmap.Y.Values)}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+		charts.WithVisualMapOpts(opts.VisualMap{
+			Calculable: opts.Bool(true),
+			Min:        float32(low),
+			Max:        float32(high),
+			InRange:    &opts.VisualMapInRange{Color: []string{"#d73027", "#fee08b", "#1a9850"}},
+		}),
+	)
+
+	chart.AddSeries(string(study.Objective), data, charts.WithLabelOpts(opts.Label{Show: opts.Bool(true), Formatter: "{@[2]}"}))
+	return chart
+}
+
+// stabilityChart compares the objective of the best trials with the mean of their neighbours.
+func stabilityChart(stabilities []*gridsearch.Stability, study *analysis) *charts.Bar {
+	names := make([]string, len(stabilities))
+	values := make([]opts.BarData, len(stabilities))
+	neighbours := make([]opts.BarData, len(stabilities))
+
+	for i, s := range stabilities {
+		names[i] = fmt.Sprintf("#%d", s.Trial.Index)
+		values[i] = opts.BarData{Value: s.Trial.Value}
+		if math.IsNaN(s.NeighbourMean) {
+			neighbours[i] = opts.BarData{Value: "-"}
+		} else {
+			neighbours[i] = opts.BarData{Value: s.NeighbourMean}
+		}
+	}
+
+	chart := charts.NewBar()
+	chart.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{Width: "900px", Height: "500px"}),
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Local stability of the best trials",
+			Subtitle: fmt.Sprintf("%s of each trial and mean of its neighbours", study.Objective),
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Right: "0"}),
+	)
+
+	chart.SetXAxis(names).
+		AddSeries("Trial", values).
+		AddSeries("Neighbours", neighbours)
+	return chart
+}
+
+func printStabilities(stabilities []*gridsearch.Stability, study *analysis) {
+	fmt.Printf("\n🧭 Local stability of the best trials of %s (%s)\n", study.Name, study.Objective)
+	fmt.Printf("==========================================\n\n")
+
+	fmt.Printf("%6s │ %10s │ %10s │ %10s │ %10s\n", "Trial", "Objective", "Neighbours", "Mean", "Score")
+
+	for _, s := range stabilities {
+		icon := "✅"
+		switch {
+		case s.Neighbours == 0:
+			icon = "❔"
+		case s.Score < isolatedPeakScore:
+			icon = "⚠️ "
+		}
+
+		fmt.Printf("%6d │ %10.4f │ %10d │ %10.4f │ %10.2f %s %v\n",
+			s.Trial.Index, s.Trial.Value, s.Neighbours, s.NeighbourMean, s.Score, icon, s.Trial.Combo)
+	}
+
+	fmt.Printf("\n⚠️  isolated peak (neighbours over %.0f standard deviation worse), ❔ no evaluated neighbour\n", -isolatedPeakScore)
+}
+
+func labels(values []interface{}) []string {
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = fmt.Sprintf("%v", v)
+	}
+	return result
+}
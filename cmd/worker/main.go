@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"trading-bot/runner"
+)
+
+func main() {
+	coordinator := flag.String("coordinator", "http://localhost:8082", "URL of the coordinator (gridsearch -serve)")
+	concurrency := flag.Int("concurrency", runtime.NumCPU(), "number of jobs run in parallel")
+	datasetBudget := flag.Int64("dataset-budget", runner.DefaultDatasetBudget>>20, "memory budget of the dataset cache, in MiB")
+	flag.Parse()
+
+	// Ctrl-C finishes running jobs, their leases are not renewed otherwise
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("👷 Running jobs of %s on %d goroutines\n", *coordinator, *concurrency)
+
+	worker := runner.NewWorker(*coordinator)
+	worker.SetDatasetBudget(*datasetBudget << 20)
+	worker.Run(ctx, *concurrency)
+}
@@ -224,6 +224,14 @@ func (s *Session) IsOpen(t time.Time) bool {
 	return !localTime.Before(start) && !localTime.After(end)
 }
 
+// End returns the end of the session on the day of t, in the session's time zone.
+func (s *Session) End(t time.Time) time.Time {
+	localTime := t.In(s.location)
+
+	return time.Date(localTime.Year(), localTime.Month(), localTime.Day(),
+		s.endHour, s.endMin, 0, 0, s.location)
+}
+
 func (s *Session) String() string {
 	return s.name
 }
@@ -239,3 +247,12 @@ var (
 		return NewSession("New York", 9, 0, 17, 0, loc)
 	}()
 )
+
+// WeekClose returns the weekly close of the forex market in the week of t: Friday 17:00 in New York.
+// The week starts when the market opens again, on Sunday.
+func WeekClose(t time.Time) time.Time {
+	localTime := t.In(NYSession.location)
+	friday := localTime.AddDate(0, 0, int(time.Friday-localTime.Weekday()))
+
+	return time.Date(friday.Year(), friday.Month(), friday.Day(), 17, 0, 0, 0, NYSession.location)
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -18,6 +19,58 @@ func FromDate(t time.Time) Month {
 	return Month{year: t.Year(), month: int(t.Month())}
 }
 
+// ParseMonth parses a month formatted by Month.String (e.g., 2023-01).
+func ParseMonth(s string) (Month, error) {
+	var year, month int
+	if _, err := fmt.Sscanf(s, "%04d-%02d", &year, &month); err != nil {
+		return Month{}, fmt.Errorf("invalid month '%s': %w", s, err)
+	}
+	if month < 1 || month > 12 {
+		return Month{}, fmt.Errorf("invalid month '%s': month out of range", s)
+	}
+
+	return NewMonth(year, month), nil
+}
+
+// ParseMonthRange parses a range formatted by FormatMonthRange (e.g., 2023-01..2023-06, or 2023-01 for a single month).
+func ParseMonthRange(s string) (Month, Month, error) {
+	first, last, isRange := strings.Cut(s, "..")
+	if !isRange {
+		last = first
+	}
+
+	from, err := ParseMonth(first)
+	if err != nil {
+		return Month{}, Month{}, err
+	}
+	to, err := ParseMonth(last)
+	if err != nil {
+		return Month{}, Month{}, err
+	}
+	if to.Before(from) {
+		return Month{}, Month{}, fmt.Errorf("invalid month range '%s': end before start", s)
+	}
+
+	return from, to, nil
+}
+
+// FormatMonthRange formats a range of months, inclusive. A single month is formatted as the month itself.
+func FormatMonthRange(from, to Month) string {
+	if from == to {
+		return from.String()
+	}
+	return from.String() + ".." + to.String()
+}
+
+// Months returns the months from from to to, inclusive.
+func Months(from, to Month) []Month {
+	months := make([]Month, 0)
+	for m := from; !to.Before(m); m = m.AddMonths(1) {
+		months = append(months, m)
+	}
+	return months
+}
+
 func (m Month) Year() int {
 	return m.year
 }
@@ -30,6 +83,14 @@ func (m Month) String() string {
 	return fmt.Sprintf("%04d-%02d", m.year, m.month)
 }
 
+func (m Month) AddMonths(n int) Month {
+	return FromDate(m.FirstDay().AddDate(0, n, 0))
+}
+
+func (m Month) Before(other Month) bool {
+	return m.year < other.year || (m.year == other.year && m.month < other.month)
+}
+
 func (m Month) FirstDay() time.Time {
 	return time.Date(m.year, time.Month(m.month), 1, 0, 0, 0, 0, time.UTC)
 }
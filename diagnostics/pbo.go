@@ -0,0 +1,160 @@
+// Package diagnostics measures the data-snooping bias of an optimisation, from the monthly results of its strategies.
+package diagnostics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// PBOResult is the outcome of the combinatorially symmetric cross-validation (CSCV) of an optimisation.
+type PBOResult struct {
+	PBO             float64   // Probability that the best in-sample strategy is below the out-of-sample median
+	Splits          int       // Number of blocks of months
+	Combinations    int       // Number of in-sample/out-of-sample partitions
+	Logits          []float64 // Of the out-of-sample relative rank of the best in-sample strategy, per partition
+	MedianLogit     float64
+	ProbabilityLoss float64 // Probability that the best in-sample strategy loses out-of-sample
+}
+
+// PBO estimates the Probability of Backtest Overfitting (Bailey, Borwein, López de Prado and Zhu, 2015).
+// pnl has a row per month and a column per strategy.
+//
+// The months are split in splits contiguous blocks, and every half of the blocks is used as in-sample period once,
+// the other half being out-of-sample. For each partition, the strategy with the best in-sample Sharpe ratio
+// is ranked among all strategies out-of-sample; the PBO is the share of partitions where it is below the median.
+// splits is reduced to the number of months if needed, and must be even.
+func PBO(pnl [][]float64, splits int) (*PBOResult, error) {
+	months := len(pnl)
+	if months == 0 {
+		return nil, fmt.Errorf("no months")
+	}
+	strategies := len(pnl[0])
+	if strategies < 2 {
+		return nil, fmt.Errorf("PBO needs 2 strategies at least, got %d", strategies)
+	}
+	for _, row := range pnl {
+		if len(row) != strategies {
+			return nil, fmt.Errorf("months have results of different numbers of strategies")
+		}
+	}
+
+	splits = min(splits, months)
+	splits -= splits % 2
+	if splits < 2 {
+		return nil, fmt.Errorf("PBO needs 2 months at least, got %d", months)
+	}
+
+	blocks := make([][]int, splits) // Months of each block
+	for m := 0; m < months; m++ {
+		block := m * splits / months
+		blocks[block] = append(blocks[block], m)
+	}
+
+	result := &PBOResult{Splits: splits}
+	var overfit, losses int
+
+	for _, inSampleBlocks := range combinations(splits, splits/2) {
+		var inSample, outOfSample []int
+		for b, blockMonths := range blocks {
+			if slices.Contains(inSampleBlocks, b) {
+				inSample = append(inSample, blockMonths...)
+			} else {
+				outOfSample = append(outOfSample, blockMonths...)
+			}
+		}
+
+		inSamplePerformance := performances(pnl, inSample)
+		outOfSamplePerformance := performances(pnl, outOfSample)
+
+		best := 0
+		for s, performance := range inSamplePerformance {
+			if performance > inSamplePerformance[best] {
+				best = s
+			}
+		}
+
+		// Relative rank in ]0, 1[, ties get their mean rank
+		rank := 1.0
+		for s, performance := range outOfSamplePerformance {
+			if performance < outOfSamplePerformance[best] {
+				rank++
+			} else if performance == outOfSamplePerformance[best] && s != best {
+				rank += 0.5
+			}
+		}
+		omega := rank / float64(strategies+1)
+		logit := math.Log(omega / (1 - omega))
+
+		result.Logits = append(result.Logits, logit)
+		if logit <= 0 {
+			overfit++
+		}
+		if outOfSampleMean(pnl, outOfSample, best) < 0 {
+			losses++
+		}
+	}
+
+	result.Combinations = len(result.Logits)
+	result.PBO = float64(overfit) / float64(result.Combinations)
+	result.ProbabilityLoss = float64(losses) / float64(result.Combinations)
+	result.MedianLogit = median(result.Logits)
+
+	return result, nil
+}
+
+// performances returns the Sharpe ratio of each strategy over the months.
+func performances(pnl [][]float64, months []int) []float64 {
+	values := make([]float64, len(pnl[0]))
+	column := make([]float64, len(months))
+
+	for s := range values {
+		for i, m := range months {
+			column[i] = pnl[m][s]
+		}
+		values[s] = sharpe(column)
+	}
+
+	return values
+}
+
+func outOfSampleMean(pnl [][]float64, months []int, strategy int) float64 {
+	var total float64
+	for _, m := range months {
+		total += pnl[m][strategy]
+	}
+	return total / float64(len(months))
+}
+
+// combinations returns every subset of k elements of 0..n-1, in lexicographic order.
+func combinations(n, k int) [][]int {
+	var result [][]int
+	current := make([]int, 0, k)
+
+	var helper func(start int)
+	helper = func(start int) {
+		if len(current) == k {
+			result = append(result, slices.Clone(current))
+			return
+		}
+		for i := start; i <= n-(k-len(current)); i++ {
+			current = append(current, i)
+			helper(i + 1)
+			current = current[:len(current)-1]
+		}
+	}
+
+	helper(0)
+	return result
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,128 @@
+package diagnostics
+
+import (
+	"fmt"
+	"math"
+)
+
+// eulerMascheroni is the Euler-Mascheroni constant, of the expected maximum of normal variables.
+const eulerMascheroni = 0.5772156649015329
+
+// SharpeResult is the deflated Sharpe ratio of a strategy.
+type SharpeResult struct {
+	Sharpe         float64 // Monthly, not annualised
+	Skewness       float64
+	Kurtosis       float64 // Not excess, 3 for normal returns
+	ExpectedMax    float64 // Expected maximum Sharpe ratio of the trials if none had an edge
+	DeflatedSharpe float64 // Probability that the true Sharpe ratio is above ExpectedMax
+}
+
+// DeflatedSharpe computes the deflated Sharpe ratio (Bailey and López de Prado, 2014) of the monthly PnL of a strategy,
+// selected among trials strategies whose Sharpe ratios have the variance trialsVariance.
+//
+// The more strategies are tried, the higher the best Sharpe ratio is by chance alone: the deflated Sharpe ratio is the
+// probability that the strategy beats this expected maximum, given the length, skewness and kurtosis of its returns.
+func DeflatedSharpe(pnl []float64, trials int, trialsVariance float64) (*SharpeResult, error) {
+	if len(pnl) < 3 {
+		return nil, fmt.Errorf("deflated Sharpe ratio needs 3 months at least, got %d", len(pnl))
+	}
+	if trials < 1 {
+		return nil, fmt.Errorf("deflated Sharpe ratio needs 1 trial at least")
+	}
+
+	result := &SharpeResult{Sharpe: sharpe(pnl)}
+	result.Skewness, result.Kurtosis = moments(pnl)
+
+	if trials > 1 {
+		result.ExpectedMax = math.Sqrt(trialsVariance) *
+			((1-eulerMascheroni)*normalQuantile(1-1/float64(trials)) +
+				eulerMascheroni*normalQuantile(1-1/(float64(trials)*math.E)))
+	}
+
+	sr := result.Sharpe
+	variance := 1 - result.Skewness*sr + (result.Kurtosis-1)/4*sr*sr
+	if variance <= 0 {
+		// Degenerate moments (e.g., constant PnL), the estimation error is unknown
+		variance = 1
+	}
+
+	result.DeflatedSharpe = normalCDF((sr - result.ExpectedMax) * math.Sqrt(float64(len(pnl)-1)) / math.Sqrt(variance))
+	return result, nil
+}
+
+// SharpeVariance returns the variance of the Sharpe ratios of the strategies, a column of pnl each.
+func SharpeVariance(pnl [][]float64) float64 {
+	if len(pnl) == 0 {
+		return 0
+	}
+
+	sharpes := performances(pnl, monthIndices(len(pnl)))
+	return variance(sharpes)
+}
+
+// sharpe returns the ratio of the mean to the standard deviation of the PnL, 0 if the PnL is constant.
+func sharpe(pnl []float64) float64 {
+	stdev := math.Sqrt(variance(pnl))
+	if stdev == 0 {
+		return 0
+	}
+	return mean(pnl) / stdev
+}
+
+func mean(values []float64) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// variance is the sample variance, 0 for a single value.
+func variance(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+
+	m := mean(values)
+	var sq float64
+	for _, v := range values {
+		sq += (v - m) * (v - m)
+	}
+	return sq / float64(len(values)-1)
+}
+
+// moments returns the skewness and the kurtosis of the values, those of a normal distribution if they are constant.
+func moments(values []float64) (float64, float64) {
+	m := mean(values)
+
+	var m2, m3, m4 float64
+	for _, v := range values {
+		d := v - m
+		m2 += d * d
+		m3 += d * d * d
+		m4 += d * d * d * d
+	}
+	n := float64(len(values))
+	m2, m3, m4 = m2/n, m3/n, m4/n
+
+	if m2 == 0 {
+		return 0, 3
+	}
+	return m3 / math.Pow(m2, 1.5), m4 / (m2 * m2)
+}
+
+func monthIndices(n int) []int {
+	indices := make([]int, n)
+	for i := range indices {
+		indices[i] = i
+	}
+	return indices
+}
+
+func normalCDF(x float64) float64 {
+	return 0.5 * (1 + math.Erf(x/math.Sqrt2))
+}
+
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
@@ -0,0 +1,30 @@
+package diagnostics
+
+// Verdict summarises whether a strategy is likely to hold out-of-sample.
+type Verdict string
+
+const (
+	VerdictSignificant  Verdict = "significant"  // Its Sharpe ratio beats the trials, and the selection does not overfit
+	VerdictInconclusive Verdict = "inconclusive" // Not enough evidence either way
+	VerdictOverfit      Verdict = "overfit"      // Its Sharpe ratio is explained by the number of trials
+)
+
+// Thresholds of the verdicts
+const (
+	SignificantDeflatedSharpe = 0.95
+	OverfitDeflatedSharpe     = 0.5
+	OverfitPBO                = 0.5
+)
+
+// Judge returns the verdict of a strategy, from its deflated Sharpe ratio and the PBO of the optimisation it was selected by.
+// A selection that overfits (PBO above one half) cannot make a strategy significant.
+func Judge(deflatedSharpe, pbo float64) Verdict {
+	switch {
+	case deflatedSharpe < OverfitDeflatedSharpe:
+		return VerdictOverfit
+	case deflatedSharpe >= SignificantDeflatedSharpe && pbo <= OverfitPBO:
+		return VerdictSignificant
+	default:
+		return VerdictInconclusive
+	}
+}
@@ -0,0 +1,201 @@
+package evolution
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"trading-bot/common"
+)
+
+var log = common.NewLogger("evolution")
+
+// Evaluator returns the objective of each genome, higher is better.
+// Genomes that cannot be evaluated have an objective of -Inf.
+type Evaluator func(ctx context.Context, genomes []*Genome) ([]float64, error)
+
+type Config struct {
+	Seed        int64
+	Population  int
+	Generations int
+
+	MaxDepth         int     // Maximum depth of each trigger, 1 for a single condition
+	ParsimonyPenalty float64 // Subtracted from the objective per node, so that simpler genomes win ties
+
+	TournamentSize int     // Individuals competing to be selected as parent
+	Elites         int     // Best individuals kept unchanged in the next generation
+	CrossoverRate  float64 // Probability that two parents are crossed over
+	MutationRate   float64 // Probability that a child is mutated
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Seed:             1,
+		Population:       30,
+		Generations:      10,
+		MaxDepth:         3,
+		ParsimonyPenalty: 0.01,
+		TournamentSize:   3,
+		Elites:           2,
+		CrossoverRate:    0.7,
+		MutationRate:     0.3,
+	}
+}
+
+type Individual struct {
+	Genome    *Genome
+	Objective float64 // Returned by the evaluator
+	Fitness   float64 // Objective minus the parsimony penalty
+}
+
+// Evolve evolves a random population, and returns the last generation, best first.
+// With a deterministic evaluator, the same seed always evolves the same genomes.
+// Genomes already evaluated in a previous generation are not evaluated again.
+func Evolve(ctx context.Context, config *Config, evaluate Evaluator) ([]*Individual, error) {
+	if config.Population < 2 {
+		return nil, fmt.Errorf("population must be 2 at least, got %d", config.Population)
+	}
+	if config.Generations < 1 {
+		return nil, fmt.Errorf("generations must be 1 at least, got %d", config.Generations)
+	}
+	if config.MaxDepth < 1 {
+		return nil, fmt.Errorf("max depth must be 1 at least, got %d", config.MaxDepth)
+	}
+	if config.Elites >= config.Population {
+		return nil, fmt.Errorf("elites (%d) must be fewer than the population (%d)", config.Elites, config.Population)
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+	objectives := make(map[string]float64) // By genome identity
+
+	genomes := make([]*Genome, config.Population)
+	for i := range genomes {
+		genomes[i] = &Genome{
+			LongTrigger:  randomTree(rng, config.MaxDepth),
+			ShortTrigger: randomTree(rng, config.MaxDepth),
+		}
+	}
+
+	var population []*Individual
+
+	for generation := 0; ; generation++ {
+		var err error
+		population, err = evaluatePopulation(ctx, config, genomes, objectives, evaluate)
+		if err != nil {
+			return nil, err
+		}
+
+		best := population[0]
+		log.Info("Generation %d: best fitness %.4f (objective %.4f, %d nodes)",
+			generation, best.Fitness, best.Objective, best.Genome.Size())
+
+		if generation+1 == config.Generations {
+			return population, nil
+		}
+
+		genomes = nextGeneration(rng, config, population)
+	}
+}
+
+// evaluatePopulation evaluates the new genomes, and returns the population sorted by fitness, best first.
+func evaluatePopulation(ctx context.Context, config *Config, genomes []*Genome, objectives map[string]float64, evaluate Evaluator) ([]*Individual, error) {
+	var unknown []*Genome
+	submitted := make(map[string]bool)
+
+	for _, genome := range genomes {
+		identity := genome.Identity()
+		if _, exists := objectives[identity]; !exists && !submitted[identity] {
+			unknown = append(unknown, genome)
+			submitted[identity] = true
+		}
+	}
+
+	if len(unknown) > 0 {
+		values, err := evaluate(ctx, unknown)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != len(unknown) {
+			return nil, fmt.Errorf("expected %d objectives, got %d", len(unknown), len(values))
+		}
+
+		for i, genome := range unknown {
+			objectives[genome.Identity()] = values[i]
+		}
+	}
+
+	population := make([]*Individual, len(genomes))
+	for i, genome := range genomes {
+		objective := objectives[genome.Identity()]
+		population[i] = &Individual{
+			Genome:    genome,
+			Objective: objective,
+			Fitness:   objective - config.ParsimonyPenalty*float64(genome.Size()),
+		}
+	}
+
+	slices.SortStableFunc(population, func(a, b *Individual) int {
+		switch {
+		case a.Fitness > b.Fitness:
+			return -1
+		case a.Fitness < b.Fitness:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return population, nil
+}
+
+// nextGeneration keeps the elites, and breeds the rest from parents selected by tournament.
+func nextGeneration(rng *rand.Rand, config *Config, population []*Individual) []*Genome {
+	genomes := make([]*Genome, 0, config.Population)
+	for _, elite := range population[:config.Elites] {
+		genomes = append(genomes, elite.Genome)
+	}
+
+	for len(genomes) < config.Population {
+		a := tournament(rng, config, population).Genome.Clone()
+		b := tournament(rng, config, population).Genome.Clone()
+
+		if rng.Float64() < config.CrossoverRate {
+			a.LongTrigger, b.LongTrigger = crossover(rng, a.LongTrigger, b.LongTrigger, config.MaxDepth)
+			a.ShortTrigger, b.ShortTrigger = crossover(rng, a.ShortTrigger, b.ShortTrigger, config.MaxDepth)
+		}
+
+		for _, child := range []*Genome{a, b} {
+			if rng.Float64() < config.MutationRate {
+				if rng.Intn(2) == 0 {
+					child.LongTrigger = mutate(rng, child.LongTrigger, config.MaxDepth)
+				} else {
+					child.ShortTrigger = mutate(rng, child.ShortTrigger, config.MaxDepth)
+				}
+			}
+
+			if len(genomes) < config.Population {
+				genomes = append(genomes, child)
+			}
+		}
+	}
+
+	return genomes
+}
+
+// tournament returns the fittest of random individuals.
+func tournament(rng *rand.Rand, config *Config, population []*Individual) *Individual {
+	var best *Individual
+	for i := 0; i < max(1, config.TournamentSize); i++ {
+		candidate := population[rng.Intn(len(population))]
+		if best == nil || candidate.Fitness > best.Fitness {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// IsFailed returns true if the individual could not be evaluated.
+func (i *Individual) IsFailed() bool {
+	return math.IsInf(i.Objective, -1)
+}
@@ -0,0 +1,216 @@
+package evolution
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// maxAttempts bounds the retries of an operator whose result exceeds the depth limit.
+const maxAttempts = 10
+
+// location is a node of a tree, with its position.
+type location struct {
+	node   *Node
+	parent *Node // nil for the root
+	index  int   // Index in the children of the parent
+	depth  int   // 1 for the root
+}
+
+func locations(root *Node) []*location {
+	var result []*location
+
+	var walk func(node, parent *Node, index, depth int)
+	walk = func(node, parent *Node, index, depth int) {
+		result = append(result, &location{node: node, parent: parent, index: index, depth: depth})
+		for i, child := range node.Children {
+			walk(child, node, i, depth+1)
+		}
+	}
+
+	walk(root, nil, 0, 1)
+	return result
+}
+
+// replace returns the root of the tree, with the node at the location replaced.
+func replace(root *Node, loc *location, node *Node) *Node {
+	if loc.parent == nil {
+		return node
+	}
+	loc.parent.Children[loc.index] = node
+	return root
+}
+
+// mutate returns a mutated copy of the tree, of at most maxDepth levels.
+// The tree is unchanged if no mutation fits the depth limit.
+func mutate(rng *rand.Rand, tree *Node, maxDepth int) *Node {
+	mutations := []func(*rand.Rand, *Node, int) *Node{
+		tweakLeaf,
+		replaceSubtree,
+		addBranch,
+		removeBranch,
+	}
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		mutated := pick(rng, mutations)(rng, tree.Clone(), maxDepth)
+		if mutated != nil && mutated.Depth() <= maxDepth {
+			return mutated
+		}
+	}
+
+	return tree.Clone()
+}
+
+// tweakLeaf changes a parameter of a leaf: a period, a threshold, a direction or an indicator.
+func tweakLeaf(rng *rand.Rand, tree *Node, maxDepth int) *Node {
+	var leaves []*Node
+	for _, loc := range locations(tree) {
+		if !isBranch(loc.node.Kind) {
+			leaves = append(leaves, loc.node)
+		}
+	}
+
+	leaf := pick(rng, leaves)
+
+	switch rng.Intn(3) {
+	case 0:
+		flipDirection(leaf)
+
+	case 1:
+		switch leaf.Kind {
+		case "threshold":
+			leaf.Threshold = math.Round(math.Max(minThreshold, math.Min(maxThreshold, leaf.Threshold+rng.NormFloat64()*10)))
+		case "slope":
+			leaf.Period = clamp(leaf.Period+pick(rng, []int{-1, 1}), minSlopePeriod, maxSlopePeriod)
+		case "compare", "crossover":
+			tweakPair(rng, leaf)
+		default:
+			tweakPeriod(rng, pick(rng, leaf.Indicators))
+		}
+
+	case 2:
+		// Swap the indicator for another one of the same family
+		i := rng.Intn(len(leaf.Indicators))
+		if slices.Contains(oscillators, leaf.Indicators[i].Kind) {
+			leaf.Indicators[i] = randomOscillator(rng)
+		} else if len(leaf.Indicators) == 2 {
+			leaf.Indicators = randomPriceFollowPair(rng)
+		} else {
+			leaf.Indicators[i] = randomPriceFollow(rng)
+		}
+	}
+
+	return tree
+}
+
+func flipDirection(leaf *Node) {
+	opposites := map[string]string{
+		"above": "below", "below": "above",
+		"up": "down", "down": "up",
+		"rising": "falling", "falling": "rising",
+	}
+	leaf.Direction = opposites[leaf.Direction]
+}
+
+// tweakPeriod changes the period by up to 25%, by 1 at least.
+func tweakPeriod(rng *rand.Rand, indicator *Indicator) {
+	delta := int(math.Round(float64(indicator.Period) * 0.25 * rng.NormFloat64()))
+	if delta == 0 {
+		delta = pick(rng, []int{-1, 1})
+	}
+
+	if slices.Contains(oscillators, indicator.Kind) {
+		indicator.Period = clamp(indicator.Period+delta, minOscillatorPeriod, maxOscillatorPeriod)
+	} else {
+		indicator.Period = clamp(indicator.Period+delta, minPricePeriod, maxPricePeriod)
+	}
+}
+
+// tweakPair changes a period of the slow and fast indicators of a leaf, keeping the slow one first with a longer period
+// (see randomPriceFollowPair): equal periods would make the leaf constant. The leaf is unchanged if no tweak keeps them different.
+func tweakPair(rng *rand.Rand, leaf *Node) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		slow, fast := *leaf.Indicators[0], *leaf.Indicators[1]
+		tweakPeriod(rng, pick(rng, []*Indicator{&slow, &fast}))
+
+		if slow.Period == fast.Period {
+			continue
+		}
+		if slow.Period < fast.Period {
+			slow, fast = fast, slow
+		}
+		leaf.Indicators = []*Indicator{&slow, &fast}
+		return
+	}
+}
+
+// replaceSubtree replaces a node by a random tree.
+func replaceSubtree(rng *rand.Rand, tree *Node, maxDepth int) *Node {
+	loc := pick(rng, locations(tree))
+	return replace(tree, loc, randomTree(rng, maxDepth-loc.depth+1))
+}
+
+// addBranch adds a random leaf to an And/Or branch, or combines a node with a random leaf in a new branch.
+func addBranch(rng *rand.Rand, tree *Node, maxDepth int) *Node {
+	loc := pick(rng, locations(tree))
+
+	if isBranch(loc.node.Kind) && len(loc.node.Children) < maxChildren {
+		loc.node.Children = append(loc.node.Children, randomLeaf(rng))
+		return tree
+	}
+
+	branch := &Node{Kind: pick(rng, branchKinds), Children: []*Node{loc.node, randomLeaf(rng)}}
+	return replace(tree, loc, branch)
+}
+
+// removeBranch removes a child of an And/Or branch, the branch is replaced by its last child.
+func removeBranch(rng *rand.Rand, tree *Node, maxDepth int) *Node {
+	var branches []*location
+	for _, loc := range locations(tree) {
+		if isBranch(loc.node.Kind) {
+			branches = append(branches, loc)
+		}
+	}
+
+	if len(branches) == 0 {
+		return nil
+	}
+
+	loc := pick(rng, branches)
+	i := rng.Intn(len(loc.node.Children))
+	loc.node.Children = slices.Delete(loc.node.Children, i, i+1)
+
+	if len(loc.node.Children) == 1 {
+		return replace(tree, loc, loc.node.Children[0])
+	}
+	return tree
+}
+
+// crossover swaps random subtrees of copies of two trees, both of at most maxDepth levels.
+// The trees are copied unchanged if no swap fits the depth limit.
+func crossover(rng *rand.Rand, a, b *Node, maxDepth int) (*Node, *Node) {
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		childA, childB := a.Clone(), b.Clone()
+		locA, locB := pick(rng, locations(childA)), pick(rng, locations(childB))
+
+		subtreeA, subtreeB := locA.node, locB.node
+		childA = replace(childA, locA, subtreeB)
+		childB = replace(childB, locB, subtreeA)
+
+		if childA.Depth() <= maxDepth && childB.Depth() <= maxDepth {
+			return childA, childB
+		}
+	}
+
+	return a.Clone(), b.Clone()
+}
+
+func clamp(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
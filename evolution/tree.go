@@ -0,0 +1,264 @@
+package evolution
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+	"trading-bot/traders/modular"
+	"trading-bot/traders/modular/conditions"
+)
+
+// Node is a node of a condition tree: an And/Or branch, or a leaf condition on indicators.
+// Kinds and directions are the keys of the JSON registry of the modular framework.
+type Node struct {
+	Kind       string       `json:"kind"`                 // and, or, threshold, compare, crossover, slope, priceThreshold
+	Children   []*Node      `json:"children,omitempty"`   // Branches of and/or
+	Indicators []*Indicator `json:"indicators,omitempty"` // Operands of a leaf
+	Period     int          `json:"period,omitempty"`     // Of slope
+	Threshold  float64      `json:"threshold,omitempty"`  // Of threshold
+	Direction  string       `json:"direction,omitempty"`  // above/below, up/down or rising/falling
+}
+
+type Indicator struct {
+	Kind   string `json:"kind"` // rsi, adx, ema
+	Period int    `json:"period"`
+}
+
+// Genome is an evolved strategy: the triggers of a modular trader, whose other parts come from a template.
+type Genome struct {
+	LongTrigger  *Node `json:"longTrigger"`
+	ShortTrigger *Node `json:"shortTrigger"`
+}
+
+// Ranges of the generated parameters
+var (
+	oscillators  = []string{"rsi", "adx"} // Bounded between 0 and 100
+	priceFollows = []string{"ema"}        // Follow the price
+
+	minOscillatorPeriod, maxOscillatorPeriod = 5, 30
+	minPricePeriod, maxPricePeriod           = 3, 200
+	minThreshold, maxThreshold               = 10.0, 90.0
+	minSlopePeriod, maxSlopePeriod           = 1, 10
+
+	leafKinds   = []string{"threshold", "compare", "crossover", "slope", "priceThreshold"}
+	branchKinds = []string{"and", "or"}
+
+	maxChildren = 4
+)
+
+// branchProbability is the probability of a branch instead of a leaf in a random tree, when depth allows.
+const branchProbability = 0.4
+
+func isBranch(kind string) bool {
+	return slices.Contains(branchKinds, kind)
+}
+
+// Depth is 1 for a leaf.
+func (n *Node) Depth() int {
+	depth := 0
+	for _, child := range n.Children {
+		depth = max(depth, child.Depth())
+	}
+	return depth + 1
+}
+
+// Size is the number of nodes of the tree.
+func (n *Node) Size() int {
+	size := 1
+	for _, child := range n.Children {
+		size += child.Size()
+	}
+	return size
+}
+
+func (n *Node) Clone() *Node {
+	clone := *n
+	clone.Children = make([]*Node, len(n.Children))
+	for i, child := range n.Children {
+		clone.Children[i] = child.Clone()
+	}
+	clone.Indicators = make([]*Indicator, len(n.Indicators))
+	for i, indicator := range n.Indicators {
+		copied := *indicator
+		clone.Indicators[i] = &copied
+	}
+	return &clone
+}
+
+func (g *Genome) Clone() *Genome {
+	return &Genome{LongTrigger: g.LongTrigger.Clone(), ShortTrigger: g.ShortTrigger.Clone()}
+}
+
+func (g *Genome) Size() int {
+	return g.LongTrigger.Size() + g.ShortTrigger.Size()
+}
+
+// Identity is a stable serialization of the genome.
+func (g *Genome) Identity() string {
+	data, err := json.Marshal(g)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// toJSON returns the condition in the format of the JSON registry of the modular framework.
+func (n *Node) toJSON() any {
+	switch n.Kind {
+	case "and", "or":
+		children := make([]any, len(n.Children))
+		for i, child := range n.Children {
+			children[i] = child.toJSON()
+		}
+		return map[string]any{n.Kind: children}
+
+	case "threshold":
+		return map[string]any{n.Kind: map[string]any{
+			"indicator": n.Indicators[0].toJSON(),
+			"threshold": n.Threshold,
+			"direction": n.Direction,
+		}}
+
+	case "compare":
+		return map[string]any{n.Kind: map[string]any{
+			"indicatorA": n.Indicators[0].toJSON(),
+			"indicatorB": n.Indicators[1].toJSON(),
+			"direction":  n.Direction,
+		}}
+
+	case "crossover":
+		return map[string]any{n.Kind: map[string]any{
+			"reference": n.Indicators[0].toJSON(),
+			"test":      n.Indicators[1].toJSON(),
+			"direction": n.Direction,
+		}}
+
+	case "slope":
+		return map[string]any{n.Kind: map[string]any{
+			"indicator": n.Indicators[0].toJSON(),
+			"period":    n.Period,
+			"direction": n.Direction,
+		}}
+
+	case "priceThreshold":
+		return map[string]any{n.Kind: map[string]any{
+			"indicator": n.Indicators[0].toJSON(),
+			"direction": n.Direction,
+		}}
+
+	default:
+		panic(fmt.Sprintf("unknown node kind: %s", n.Kind))
+	}
+}
+
+func (i *Indicator) toJSON() any {
+	return map[string]any{i.Kind: i.Period}
+}
+
+// Condition returns the condition of the tree, parsed by the JSON registry of the modular framework.
+func (n *Node) Condition() (conditions.Condition, error) {
+	data, err := json.Marshal(n.toJSON())
+	if err != nil {
+		return nil, err
+	}
+
+	return conditions.FromJSON(data)
+}
+
+// SetTriggers sets the triggers of the genome on a builder, whose other parts (e.g., filter, risk manager) are fixed.
+func (g *Genome) SetTriggers(builder modular.Builder) error {
+	longTrigger, err := g.LongTrigger.Condition()
+	if err != nil {
+		return fmt.Errorf("failed to parse long trigger: %w", err)
+	}
+
+	shortTrigger, err := g.ShortTrigger.Condition()
+	if err != nil {
+		return fmt.Errorf("failed to parse short trigger: %w", err)
+	}
+
+	builder.Strategy().SetLongTrigger(longTrigger).SetShortTrigger(shortTrigger)
+	return nil
+}
+
+// randomTree returns a random tree of at most maxDepth levels.
+func randomTree(rng *rand.Rand, maxDepth int) *Node {
+	if maxDepth <= 1 || rng.Float64() >= branchProbability {
+		return randomLeaf(rng)
+	}
+
+	children := make([]*Node, 2+rng.Intn(2))
+	for i := range children {
+		children[i] = randomTree(rng, maxDepth-1)
+	}
+
+	return &Node{Kind: pick(rng, branchKinds), Children: children}
+}
+
+func randomLeaf(rng *rand.Rand) *Node {
+	node := &Node{Kind: pick(rng, leafKinds)}
+
+	switch node.Kind {
+	case "threshold":
+		node.Indicators = []*Indicator{randomOscillator(rng)}
+		node.Threshold = randomThreshold(rng)
+		node.Direction = pick(rng, []string{"above", "below"})
+
+	case "compare":
+		node.Indicators = randomPriceFollowPair(rng)
+		node.Direction = pick(rng, []string{"above", "below"})
+
+	case "crossover":
+		node.Indicators = randomPriceFollowPair(rng)
+		node.Direction = pick(rng, []string{"up", "down"})
+
+	case "slope":
+		if rng.Intn(2) == 0 {
+			node.Indicators = []*Indicator{randomOscillator(rng)}
+		} else {
+			node.Indicators = []*Indicator{randomPriceFollow(rng)}
+		}
+		node.Period = randomInt(rng, minSlopePeriod, maxSlopePeriod)
+		node.Direction = pick(rng, []string{"rising", "falling"})
+
+	case "priceThreshold":
+		node.Indicators = []*Indicator{randomPriceFollow(rng)}
+		node.Direction = pick(rng, []string{"above", "below"})
+	}
+
+	return node
+}
+
+func randomOscillator(rng *rand.Rand) *Indicator {
+	return &Indicator{Kind: pick(rng, oscillators), Period: randomInt(rng, minOscillatorPeriod, maxOscillatorPeriod)}
+}
+
+func randomPriceFollow(rng *rand.Rand) *Indicator {
+	return &Indicator{Kind: pick(rng, priceFollows), Period: randomInt(rng, minPricePeriod, maxPricePeriod)}
+}
+
+// randomPriceFollowPair returns a slow and a fast indicator, with different periods.
+func randomPriceFollowPair(rng *rand.Rand) []*Indicator {
+	slow, fast := randomPriceFollow(rng), randomPriceFollow(rng)
+	for slow.Period == fast.Period {
+		fast = randomPriceFollow(rng)
+	}
+	if slow.Period < fast.Period {
+		slow, fast = fast, slow
+	}
+	return []*Indicator{slow, fast}
+}
+
+// randomThreshold returns a round threshold, so that close genomes share their runs.
+func randomThreshold(rng *rand.Rand) float64 {
+	return float64(randomInt(rng, int(minThreshold), int(maxThreshold)))
+}
+
+func randomInt(rng *rand.Rand, min, max int) int {
+	return min + rng.Intn(max-min+1)
+}
+
+func pick[T any](rng *rand.Rand, values []T) T {
+	return values[rng.Intn(len(values))]
+}
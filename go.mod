@@ -5,8 +5,12 @@ go 1.23.2
 toolchain go1.24.5
 
 require (
+	github.com/go-echarts/go-echarts/v2 v2.6.7
+	github.com/markcheno/go-talib v0.0.0-20250114000313-ec55a20c902f
+	github.com/mattn/go-sqlite3 v1.14.30
 	github.com/xitongsys/parquet-go v1.6.2
 	github.com/xitongsys/parquet-go-source v0.0.0-20241021075129-b732d2ac9c9b
+	gonum.org/v1/plot v0.16.0
 )
 
 require (
@@ -19,16 +23,12 @@ require (
 	github.com/apache/thrift v0.14.2 // indirect
 	github.com/campoy/embedmd v1.0.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/go-echarts/go-echarts/v2 v2.6.7 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
 	github.com/golang/snappy v0.0.3 // indirect
 	github.com/klauspost/compress v1.15.9 // indirect
-	github.com/markcheno/go-talib v0.0.0-20250114000313-ec55a20c902f // indirect
-	github.com/mattn/go-sqlite3 v1.14.30 // indirect
 	github.com/pierrec/lz4/v4 v4.1.8 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/image v0.25.0 // indirect
 	golang.org/x/text v0.23.0 // indirect
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
-	gonum.org/v1/plot v0.16.0 // indirect
 )
@@ -0,0 +1,39 @@
+package gridsearch
+
+import "fmt"
+
+// Constraint is a condition across parameters that valid combinations satisfy (e.g., a short period below a long one).
+// Invalid combinations are pruned before they are submitted.
+type Constraint struct {
+	Description string
+	Parameters  []string // Checked to be in the space
+	Check       func(combo Combo) (bool, error)
+}
+
+func NewConstraint(description string, parameters []string, check func(combo Combo) (bool, error)) *Constraint {
+	return &Constraint{Description: description, Parameters: parameters, Check: check}
+}
+
+// LessThan requires the number a to be strictly lower than the number b.
+func LessThan(a, b string) *Constraint {
+	return compare(a, b, "<", func(x, y float64) bool { return x < y })
+}
+
+// LessOrEqual requires the number a to be lower than or equal to the number b.
+func LessOrEqual(a, b string) *Constraint {
+	return compare(a, b, "<=", func(x, y float64) bool { return x <= y })
+}
+
+func compare(a, b, operator string, holds func(x, y float64) bool) *Constraint {
+	return NewConstraint(fmt.Sprintf("%s %s %s", a, operator, b), []string{a, b}, func(combo Combo) (bool, error) {
+		x, err := combo.Number(a)
+		if err != nil {
+			return false, err
+		}
+		y, err := combo.Number(b)
+		if err != nil {
+			return false, err
+		}
+		return holds(x, y), nil
+	})
+}
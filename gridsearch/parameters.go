@@ -0,0 +1,178 @@
+package gridsearch
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+type ParameterKind string
+
+const (
+	ParameterInt         ParameterKind = "int"
+	ParameterFloat       ParameterKind = "float"
+	ParameterCategorical ParameterKind = "categorical"
+	ParameterBool        ParameterKind = "bool"
+)
+
+// Parameter is a typed dimension of a parameter space, with its values in order.
+// Values are int, float64, string or bool, by kind.
+type Parameter struct {
+	Name   string
+	Kind   ParameterKind
+	Values []interface{}
+
+	err error // Invalid definition, returned by NewParameterSpace
+}
+
+func newParameter[T any](name string, kind ParameterKind, values []T) *Parameter {
+	p := &Parameter{Name: name, Kind: kind, Values: make([]interface{}, len(values))}
+	for i, value := range values {
+		p.Values[i] = value
+	}
+
+	if len(values) == 0 {
+		p.err = fmt.Errorf("parameter %s has no values", name)
+	}
+	return p
+}
+
+func invalidParameter(name string, kind ParameterKind, format string, args ...any) *Parameter {
+	return &Parameter{Name: name, Kind: kind, err: fmt.Errorf("parameter %s: %s", name, fmt.Sprintf(format, args...))}
+}
+
+// IntValues is an int parameter with explicit values.
+func IntValues(name string, values ...int) *Parameter {
+	return newParameter(name, ParameterInt, values)
+}
+
+// IntRange is an int parameter from min to max inclusive, by step (e.g., 5..50 step 5).
+func IntRange(name string, min, max, step int) *Parameter {
+	if step <= 0 || max < min {
+		return invalidParameter(name, ParameterInt, "invalid range %d..%d step %d", min, max, step)
+	}
+
+	var values []int
+	for value := min; value <= max; value += step {
+		values = append(values, value)
+	}
+	return newParameter(name, ParameterInt, values)
+}
+
+// IntLogRange is an int parameter of count values from min to max, evenly spaced on a log scale.
+// Values that round to the same int are kept once.
+func IntLogRange(name string, min, max, count int) *Parameter {
+	if min <= 0 || max < min || count < 2 {
+		return invalidParameter(name, ParameterInt, "invalid log range %d..%d with %d values", min, max, count)
+	}
+
+	var values []int
+	for _, value := range logValues(float64(min), float64(max), count) {
+		if rounded := int(math.Round(value)); !slices.Contains(values, rounded) {
+			values = append(values, rounded)
+		}
+	}
+	return newParameter(name, ParameterInt, values)
+}
+
+// FloatValues is a float parameter with explicit values.
+func FloatValues(name string, values ...float64) *Parameter {
+	return newParameter(name, ParameterFloat, values)
+}
+
+// FloatRange is a float parameter from min to max inclusive, by step (e.g., 0.5..3 step 0.5).
+func FloatRange(name string, min, max, step float64) *Parameter {
+	if step <= 0 || max < min {
+		return invalidParameter(name, ParameterFloat, "invalid range %g..%g step %g", min, max, step)
+	}
+
+	var values []float64
+	for i := 0; ; i++ {
+		// Computed from min, rounded, so that steps do not accumulate float errors (e.g., 0.30000000000000004)
+		value := roundFloat(min + float64(i)*step)
+		if value > max+step*1e-9 {
+			break
+		}
+		values = append(values, value)
+	}
+	return newParameter(name, ParameterFloat, values)
+}
+
+// FloatLogRange is a float parameter of count values from min to max, evenly spaced on a log scale.
+func FloatLogRange(name string, min, max float64, count int) *Parameter {
+	if min <= 0 || max < min || count < 2 {
+		return invalidParameter(name, ParameterFloat, "invalid log range %g..%g with %d values", min, max, count)
+	}
+
+	values := logValues(min, max, count)
+	for i := range values {
+		values[i] = roundFloat(values[i])
+	}
+	return newParameter(name, ParameterFloat, values)
+}
+
+// Categorical is a parameter with named values.
+func Categorical(name string, values ...string) *Parameter {
+	return newParameter(name, ParameterCategorical, values)
+}
+
+func Bool(name string) *Parameter {
+	return newParameter(name, ParameterBool, []bool{true, false})
+}
+
+func logValues(min, max float64, count int) []float64 {
+	values := make([]float64, count)
+	ratio := math.Log(max / min)
+	for i := range values {
+		values[i] = min * math.Exp(ratio*float64(i)/float64(count-1))
+	}
+	return values
+}
+
+// roundFloat rounds to 10 significant digits.
+func roundFloat(value float64) float64 {
+	if value == 0 {
+		return 0
+	}
+	scale := math.Pow(10, 9-math.Floor(math.Log10(math.Abs(value))))
+	return math.Round(value*scale) / scale
+}
+
+// validate checks that the values are distinct and of the kind of the parameter.
+func (p *Parameter) validate() error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.Name == "" {
+		return fmt.Errorf("parameter without name")
+	}
+	if len(p.Values) == 0 {
+		return fmt.Errorf("parameter %s has no values", p.Name)
+	}
+
+	for i, value := range p.Values {
+		if !p.Kind.accepts(value) {
+			return fmt.Errorf("parameter %s: value %v is %T, not %s", p.Name, value, value, p.Kind)
+		}
+		if slices.Contains(p.Values[:i], value) {
+			return fmt.Errorf("parameter %s: duplicate value %v", p.Name, value)
+		}
+	}
+
+	return nil
+}
+
+func (k ParameterKind) accepts(value interface{}) bool {
+	switch value.(type) {
+	case int:
+		return k == ParameterInt
+	case float64:
+		return k == ParameterFloat
+	case string:
+		return k == ParameterCategorical
+	case bool:
+		return k == ParameterBool
+	default:
+		return false
+	}
+}
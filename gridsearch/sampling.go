@@ -0,0 +1,170 @@
+package gridsearch
+
+import (
+	"crypto/md5"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// Sampler selects the combinations of a parameter space to run.
+type Sampler string
+
+const (
+	SamplerGrid           Sampler = "grid"   // Every combination (Cartesian product)
+	SamplerRandom         Sampler = "random" // Uniform random combinations
+	SamplerLatinHypercube Sampler = "lhs"    // Latin hypercube: each value of each parameter is sampled evenly
+	SamplerSobol          Sampler = "sobol"  // Scrambled Sobol sequence: low-discrepancy coverage of the space
+)
+
+var Samplers = []Sampler{SamplerGrid, SamplerRandom, SamplerLatinHypercube, SamplerSobol}
+
+func ParseSampler(name string) (Sampler, error) {
+	for _, s := range Samplers {
+		if string(s) == name {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sampler: %s", name)
+}
+
+// Size returns the number of combinations of the space, including the ones that do not satisfy the constraints.
+func (space *ParameterSpace) Size() int {
+	size := 1
+	for _, p := range space.parameters {
+		size *= len(p.Values)
+	}
+	return size
+}
+
+// Sample returns at most budget distinct combinations of the space, chosen by the sampler.
+// The same sampler, budget and seed always return the same combinations, in the same order.
+// The grid sampler, or a budget covering the whole space, returns every combination.
+// Combinations that do not satisfy the constraints are skipped, a constraint that cannot be checked is an error.
+func (space *ParameterSpace) Sample(sampler Sampler, budget int, seed int64) ([]Combo, error) {
+	if sampler == SamplerGrid || budget >= space.Size() {
+		return space.GenerateCombinations()
+	}
+	if budget <= 0 {
+		return nil, fmt.Errorf("budget must be greater than 0")
+	}
+
+	keys := space.keys
+	rng := rand.New(rand.NewSource(seed))
+
+	var points [][]float64
+	switch sampler {
+	case SamplerRandom:
+		// Draw more points than needed, as some give the same combination
+		points = randomPoints(rng, budget*10, len(keys))
+	case SamplerLatinHypercube:
+		points = latinHypercubePoints(rng, budget, len(keys))
+	case SamplerSobol:
+		var err error
+		points, err = sobolPoints(rng, budget, len(keys))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown sampler: %s", sampler)
+	}
+
+	combos := make([]Combo, 0, budget)
+	seen := make(map[string]struct{})
+
+	add := func(points [][]float64) error {
+		for _, point := range points {
+			if len(combos) == budget {
+				return nil
+			}
+
+			combo := space.comboAt(keys, point)
+			valid, err := space.isValid(combo)
+			if err != nil {
+				return err
+			}
+			if !valid {
+				continue
+			}
+
+			id := combo.ID()
+			if _, exists := seen[id]; exists {
+				continue
+			}
+			seen[id] = struct{}{}
+
+			combos = append(combos, combo)
+		}
+		return nil
+	}
+
+	if err := add(points); err != nil {
+		return nil, err
+	}
+
+	// Different points may give the same combination, or invalid ones, complete with random ones
+	for attempts := 0; len(combos) < budget && attempts < 10; attempts++ {
+		if err := add(randomPoints(rng, budget*10, len(keys))); err != nil {
+			return nil, err
+		}
+	}
+
+	return combos, nil
+}
+
+// comboAt returns the combination at a point of the unit hypercube, one coordinate per parameter.
+func (space *ParameterSpace) comboAt(keys []string, point []float64) Combo {
+	combo := make(Combo, len(keys))
+	for i, key := range keys {
+		values := space.parameters[key].Values
+		index := min(int(point[i]*float64(len(values))), len(values)-1)
+		combo[key] = values[index]
+	}
+	return combo
+}
+
+// ID is a stable identifier of the combination, independent of the order of its parameters.
+func (c Combo) ID() string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%s=%T:%v;", k, c[k], c[k])
+	}
+
+	hash := md5.Sum([]byte(sb.String()))
+	return fmt.Sprintf("%x", hash[:6])
+}
+
+func randomPoints(rng *rand.Rand, n, dims int) [][]float64 {
+	points := make([][]float64, n)
+	for i := range points {
+		points[i] = make([]float64, dims)
+		for d := range points[i] {
+			points[i][d] = rng.Float64()
+		}
+	}
+	return points
+}
+
+// latinHypercubePoints splits each dimension in n strata, and places exactly one point in each stratum of each dimension.
+func latinHypercubePoints(rng *rand.Rand, n, dims int) [][]float64 {
+	points := make([][]float64, n)
+	for i := range points {
+		points[i] = make([]float64, dims)
+	}
+
+	for d := 0; d < dims; d++ {
+		strata := rng.Perm(n)
+		for i := range points {
+			points[i][d] = (float64(strata[i]) + rng.Float64()) / float64(n)
+		}
+	}
+
+	return points
+}
@@ -0,0 +1,187 @@
+package gridsearch
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Aggregation marginalises the objective over the parameters that are not pivoted.
+type Aggregation string
+
+const (
+	AggregationMean Aggregation = "mean" // Expected objective when the other parameters vary
+	AggregationMax  Aggregation = "max"  // Best objective reachable with the other parameters
+)
+
+func ParseAggregation(value string) (Aggregation, error) {
+	switch Aggregation(value) {
+	case AggregationMean, AggregationMax:
+		return Aggregation(value), nil
+	default:
+		return "", fmt.Errorf("unknown aggregation: %s", value)
+	}
+}
+
+// Heatmap is the objective of the done trials pivoted on two parameters.
+type Heatmap struct {
+	X, Y   *Parameter
+	Values [][]float64 // By index of the Y then X value, NaN without done trial
+	Counts [][]int     // Number of done trials of each cell
+}
+
+// Pivot aggregates the objective of the done trials by the values of the parameters x and y.
+func (space *ParameterSpace) Pivot(trials []*Trial, x, y string, aggregation Aggregation) (*Heatmap, error) {
+	if x == y {
+		return nil, fmt.Errorf("cannot pivot %s on itself", x)
+	}
+
+	heatmap := &Heatmap{X: space.Parameter(x), Y: space.Parameter(y)}
+	if heatmap.X == nil {
+		return nil, fmt.Errorf("parameter %s not in space", x)
+	}
+	if heatmap.Y == nil {
+		return nil, fmt.Errorf("parameter %s not in space", y)
+	}
+
+	heatmap.Values = make([][]float64, len(heatmap.Y.Values))
+	heatmap.Counts = make([][]int, len(heatmap.Y.Values))
+	for i := range heatmap.Values {
+		heatmap.Values[i] = make([]float64, len(heatmap.X.Values))
+		heatmap.Counts[i] = make([]int, len(heatmap.X.Values))
+	}
+
+	for _, trial := range trials {
+		if trial.Status != TrialStatusDone {
+			continue
+		}
+
+		xi := slices.Index(heatmap.X.Values, trial.Combo[x])
+		yi := slices.Index(heatmap.Y.Values, trial.Combo[y])
+		if xi < 0 || yi < 0 {
+			return nil, fmt.Errorf("trial %d does not fit the space", trial.Index)
+		}
+
+		count := heatmap.Counts[yi][xi]
+		switch {
+		case count == 0:
+			heatmap.Values[yi][xi] = trial.Value
+		case aggregation == AggregationMax:
+			heatmap.Values[yi][xi] = math.Max(heatmap.Values[yi][xi], trial.Value)
+		default:
+			heatmap.Values[yi][xi] += (trial.Value - heatmap.Values[yi][xi]) / float64(count+1)
+		}
+		heatmap.Counts[yi][xi]++
+	}
+
+	for yi, row := range heatmap.Counts {
+		for xi, count := range row {
+			if count == 0 {
+				heatmap.Values[yi][xi] = math.NaN()
+			}
+		}
+	}
+
+	return heatmap, nil
+}
+
+// Stability compares the objective of a trial with the one of its neighbours.
+type Stability struct {
+	Trial         *Trial
+	Neighbours    int     // Done trials that differ by one step of a single parameter
+	NeighbourMean float64 // Mean objective of the neighbours, NaN without neighbours
+	// Score is the difference between the neighbours and the trial, in standard deviations of the objective of the study.
+	// Close to 0 on a plateau, very negative for an isolated peak, NaN without neighbours.
+	Score float64
+}
+
+// LocalStability returns the stability of each done trial, in order of the trials.
+//
+// Neighbours differ by a single parameter: by the previous or the next value of a number,
+// or by any other value of a categorical or bool parameter.
+func (space *ParameterSpace) LocalStability(trials []*Trial) ([]*Stability, error) {
+	var done []*Trial
+	var indices [][]int
+	var values []float64
+
+	for _, trial := range trials {
+		if trial.Status != TrialStatusDone {
+			continue
+		}
+
+		trialIndices, err := space.indices(space.keys, trial.Combo)
+		if err != nil {
+			return nil, fmt.Errorf("trial %d does not fit the space: %w", trial.Index, err)
+		}
+
+		done = append(done, trial)
+		indices = append(indices, trialIndices)
+		values = append(values, trial.Value)
+	}
+
+	stdev := stdev(values)
+
+	stabilities := make([]*Stability, len(done))
+	for i, trial := range done {
+		s := &Stability{Trial: trial, NeighbourMean: math.NaN(), Score: math.NaN()}
+
+		var total float64
+		for j := range done {
+			if space.areNeighbours(indices[i], indices[j]) {
+				total += done[j].Value
+				s.Neighbours++
+			}
+		}
+
+		if s.Neighbours > 0 {
+			s.NeighbourMean = total / float64(s.Neighbours)
+			s.Score = 0
+			if stdev > 0 {
+				s.Score = (s.NeighbourMean - trial.Value) / stdev
+			}
+		}
+
+		stabilities[i] = s
+	}
+
+	return stabilities, nil
+}
+
+// areNeighbours returns true if the combinations of the value indices differ by one step of a single parameter.
+func (space *ParameterSpace) areNeighbours(a, b []int) bool {
+	differences := 0
+	for d, key := range space.keys {
+		if a[d] == b[d] {
+			continue
+		}
+
+		differences++
+		if differences > 1 {
+			return false
+		}
+
+		if isOrdinal(space.parameters[key].Values) && (a[d]-b[d] > 1 || b[d]-a[d] > 1) {
+			return false
+		}
+	}
+	return differences == 1
+}
+
+// stdev is the population standard deviation, 0 without values.
+func stdev(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sq / float64(len(values)))
+}
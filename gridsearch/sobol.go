@@ -0,0 +1,105 @@
+package gridsearch
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+const sobolBits = 32
+
+// sobolDirections are the primitive polynomials and initial direction numbers of the Sobol sequence,
+// for the dimensions after the first one (Joe & Kuo, new-joe-kuo-6.21201).
+var sobolDirections = []struct {
+	degree int    // Degree of the primitive polynomial
+	coeffs uint32 // Coefficients of the polynomial, excluding the leading and trailing ones
+	m      []uint32
+}{
+	{1, 0, []uint32{1}},
+	{2, 1, []uint32{1, 3}},
+	{3, 1, []uint32{1, 3, 1}},
+	{3, 2, []uint32{1, 1, 1}},
+	{4, 1, []uint32{1, 1, 3, 3}},
+	{4, 4, []uint32{1, 3, 5, 13}},
+	{5, 2, []uint32{1, 1, 5, 5, 17}},
+	{5, 4, []uint32{1, 1, 5, 5, 5}},
+	{5, 7, []uint32{1, 1, 7, 11, 19}},
+	{5, 11, []uint32{1, 1, 5, 1, 1}},
+	{5, 13, []uint32{1, 1, 1, 3, 11}},
+	{5, 14, []uint32{1, 3, 5, 5, 31}},
+	{6, 1, []uint32{1, 3, 3, 9, 7, 49}},
+	{6, 13, []uint32{1, 1, 1, 15, 21, 21}},
+	{6, 16, []uint32{1, 3, 1, 13, 27, 49}},
+	{6, 19, []uint32{1, 1, 1, 15, 7, 5}},
+	{6, 22, []uint32{1, 3, 1, 15, 13, 25}},
+	{6, 25, []uint32{1, 1, 5, 5, 19, 61}},
+	{7, 1, []uint32{1, 3, 7, 11, 23, 15, 103}},
+	{7, 4, []uint32{1, 3, 7, 13, 13, 15, 69}},
+}
+
+// MaxSobolDimensions is the maximum number of parameters supported by the Sobol sampler.
+var MaxSobolDimensions = len(sobolDirections) + 1
+
+// sobolPoints returns n points of the Sobol sequence, scrambled by a random digital shift.
+// The first point of the sequence (the origin) is skipped.
+func sobolPoints(rng *rand.Rand, n, dims int) ([][]float64, error) {
+	if dims > MaxSobolDimensions {
+		return nil, fmt.Errorf("sobol sampler supports %d parameters at most, got %d", MaxSobolDimensions, dims)
+	}
+
+	directions := make([][sobolBits]uint32, dims)
+	shifts := make([]uint32, dims)
+
+	for d := 0; d < dims; d++ {
+		directions[d] = sobolDirectionNumbers(d)
+		shifts[d] = rng.Uint32()
+	}
+
+	points := make([][]float64, n)
+	x := make([]uint32, dims)
+
+	for i := 0; i < n; i++ {
+		// Gray code: the next point flips the direction number of the lowest zero bit of the index
+		c := 0
+		for index := uint32(i); index&1 == 1; index >>= 1 {
+			c++
+		}
+
+		points[i] = make([]float64, dims)
+		for d := 0; d < dims; d++ {
+			x[d] ^= directions[d][c]
+			points[i][d] = float64(x[d]^shifts[d]) / (1 << sobolBits)
+		}
+	}
+
+	return points, nil
+}
+
+func sobolDirectionNumbers(dim int) [sobolBits]uint32 {
+	var v [sobolBits]uint32
+
+	// The first dimension is the van der Corput sequence
+	if dim == 0 {
+		for i := range v {
+			v[i] = 1 << (sobolBits - 1 - i)
+		}
+		return v
+	}
+
+	params := sobolDirections[dim-1]
+	s := params.degree
+
+	for i := 0; i < s; i++ {
+		v[i] = params.m[i] << (sobolBits - 1 - i)
+	}
+
+	for i := s; i < sobolBits; i++ {
+		v[i] = v[i-s] ^ (v[i-s] >> s)
+		for k := 1; k < s; k++ {
+			if (params.coeffs>>(s-1-k))&1 == 1 {
+				v[i] ^= v[i-k]
+			}
+		}
+	}
+
+	return v
+}
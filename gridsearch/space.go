@@ -1,69 +1,235 @@
 package gridsearch
 
 import (
+	"encoding/json"
 	"fmt"
+	"slices"
 )
 
-type ParameterSpace map[string][]interface{}
+// ParameterSpace is a set of typed parameters, and the constraints that valid combinations satisfy.
+type ParameterSpace struct {
+	parameters  map[string]*Parameter
+	keys        []string // Parameters in a stable order
+	constraints []*Constraint
+}
 
 type Combo map[string]interface{}
 
-func (space ParameterSpace) GenerateCombinations() []Combo {
-	keys := make([]string, 0, len(space))
-	for k := range space {
-		keys = append(keys, k)
+// NewParameterSpace validates the parameters and the constraints.
+func NewParameterSpace(parameters []*Parameter, constraints ...*Constraint) (*ParameterSpace, error) {
+	if len(parameters) == 0 {
+		return nil, fmt.Errorf("empty parameter space")
+	}
+
+	space := &ParameterSpace{parameters: make(map[string]*Parameter, len(parameters)), constraints: constraints}
+
+	for _, p := range parameters {
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		if _, exists := space.parameters[p.Name]; exists {
+			return nil, fmt.Errorf("duplicate parameter %s", p.Name)
+		}
+
+		space.parameters[p.Name] = p
+		space.keys = append(space.keys, p.Name)
+	}
+	slices.Sort(space.keys)
+
+	for _, c := range constraints {
+		for _, name := range c.Parameters {
+			if _, exists := space.parameters[name]; !exists {
+				return nil, fmt.Errorf("constraint %s: unknown parameter %s", c.Description, name)
+			}
+		}
+	}
+
+	// Values of a parameter have the same kind, so that a constraint that fails on a combination likely fails on all
+	first := make(Combo, len(space.keys))
+	for _, key := range space.keys {
+		first[key] = space.parameters[key].Values[0]
+	}
+	if _, err := space.isValid(first); err != nil {
+		return nil, err
+	}
+
+	return space, nil
+}
+
+// Parameter returns the parameter of a name, nil if it is not in the space.
+func (space *ParameterSpace) Parameter(name string) *Parameter {
+	return space.parameters[name]
+}
+
+// Names returns the names of the parameters, sorted.
+func (space *ParameterSpace) Names() []string {
+	return slices.Clone(space.keys)
+}
+
+// Validate checks that the combination has a value of the space for each parameter, and satisfies the constraints.
+func (space *ParameterSpace) Validate(combo Combo) error {
+	for _, key := range space.keys {
+		value, ok := combo[key]
+		if !ok {
+			return fmt.Errorf("parameter %s missing", key)
+		}
+		if !slices.Contains(space.parameters[key].Values, value) {
+			return fmt.Errorf("value %v of parameter %s not in space", value, key)
+		}
+	}
+
+	for key := range combo {
+		if _, exists := space.parameters[key]; !exists {
+			return fmt.Errorf("parameter %s not in space", key)
+		}
+	}
+
+	return space.checkConstraints(combo)
+}
+
+func (space *ParameterSpace) checkConstraints(combo Combo) error {
+	for _, c := range space.constraints {
+		ok, err := c.Check(combo)
+		if err != nil {
+			return fmt.Errorf("constraint %s: %w", c.Description, err)
+		}
+		if !ok {
+			return fmt.Errorf("constraint %s not satisfied", c.Description)
+		}
 	}
+	return nil
+}
 
-	var helper func(int, map[string]interface{})
+// isValid returns true if a combination of the values of the space satisfies the constraints,
+// and an error if a constraint cannot be checked, so that a broken constraint does not prune every combination.
+func (space *ParameterSpace) isValid(combo Combo) (bool, error) {
+	for _, c := range space.constraints {
+		ok, err := c.Check(combo)
+		if err != nil {
+			return false, fmt.Errorf("constraint %s: %w", c.Description, err)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// GenerateCombinations returns every combination that satisfies the constraints.
+func (space *ParameterSpace) GenerateCombinations() ([]Combo, error) {
+	var helper func(int, map[string]interface{}) error
 	results := []Combo{}
 
-	helper = func(index int, current map[string]interface{}) {
-		if index == len(keys) {
-			combo := make(map[string]interface{}, len(current))
+	helper = func(index int, current map[string]interface{}) error {
+		if index == len(space.keys) {
+			combo := make(Combo, len(current))
 			for k, v := range current {
 				combo[k] = v
 			}
-			results = append(results, combo)
-			return
+			valid, err := space.isValid(combo)
+			if err != nil {
+				return err
+			}
+			if valid {
+				results = append(results, combo)
+			}
+			return nil
 		}
 
-		key := keys[index]
-		for _, value := range space[key] {
+		key := space.keys[index]
+		for _, value := range space.parameters[key].Values {
 			current[key] = value
-			helper(index+1, current)
+			if err := helper(index+1, current); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
 
-	helper(0, map[string]interface{}{})
-	return results
+	if err := helper(0, map[string]interface{}{}); err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
-func comboVal[T any](c Combo, key string) T {
+func comboVal[T any](c Combo, key string) (T, error) {
+	var typed T
+
 	val, ok := c[key]
 	if !ok {
-		panic(fmt.Sprintf("Key %s not found", key))
+		return typed, fmt.Errorf("parameter %s not in combination", key)
 	}
 
-	typed, ok := val.(T)
+	typed, ok = val.(T)
 	if !ok {
-		panic(fmt.Sprintf("Value of key %s: wrong type", key))
+		return typed, fmt.Errorf("parameter %s is %T, not %T", key, val, typed)
 	}
 
-	return typed
+	return typed, nil
 }
 
-func (c Combo) Float(key string) float64 {
+func (c Combo) Float(key string) (float64, error) {
 	return comboVal[float64](c, key)
 }
 
-func (c Combo) Bool(key string) bool {
+func (c Combo) Bool(key string) (bool, error) {
 	return comboVal[bool](c, key)
 }
 
-func (c Combo) Int(key string) int {
+func (c Combo) Int(key string) (int, error) {
 	return comboVal[int](c, key)
 }
 
-func (c Combo) String(key string) string {
+func (c Combo) String(key string) (string, error) {
 	return comboVal[string](c, key)
 }
+
+// Number returns the value of an int or float parameter as a float.
+func (c Combo) Number(key string) (float64, error) {
+	switch value := c[key].(type) {
+	case int:
+		return float64(value), nil
+	case float64:
+		return value, nil
+	case nil:
+		return 0, fmt.Errorf("parameter %s not in combination", key)
+	default:
+		return 0, fmt.Errorf("parameter %s is %T, not a number", key, value)
+	}
+}
+
+// ParseCombo parses a combination serialized as JSON, with the values of the space.
+// Numbers are matched by their serialization, so that integers are restored as int.
+func (space *ParameterSpace) ParseCombo(data []byte) (Combo, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid combination: %w", err)
+	}
+
+	combo := make(Combo, len(raw))
+	for key, rawValue := range raw {
+		p, ok := space.parameters[key]
+		if !ok {
+			return nil, fmt.Errorf("parameter %s not in space", key)
+		}
+
+		found := false
+		for _, value := range p.Values {
+			serialized, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			if string(serialized) == string(rawValue) {
+				combo[key] = value
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("value %s of parameter %s not in space", rawValue, key)
+		}
+	}
+
+	return combo, nil
+}
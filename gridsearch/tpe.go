@@ -0,0 +1,290 @@
+package gridsearch
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+type TrialStatus string
+
+const (
+	TrialStatusPending TrialStatus = "pending" // Proposed, not evaluated yet
+	TrialStatusDone    TrialStatus = "done"
+	TrialStatusFailed  TrialStatus = "failed" // Could not be evaluated
+)
+
+// Trial is an evaluation of a combination by an optimiser.
+type Trial struct {
+	Index  int // Order of the proposal in the study
+	Combo  Combo
+	Status TrialStatus
+	Value  float64 // Objective of a done trial, higher is better
+}
+
+// TPE is a Tree-structured Parzen Estimator optimiser over a parameter space.
+// It splits the evaluated trials in good and bad ones, models the distribution of the parameters of each group,
+// and proposes the combinations that are the most likely to be good rather than bad.
+//
+// TPE has no state besides its trials, so a study is resumed by proposing again from its saved trials.
+type TPE struct {
+	Space *ParameterSpace
+	Seed  int64
+
+	StartupTrials int     // Random trials before the model is used
+	Gamma         float64 // Fraction of the trials considered good
+	Candidates    int     // Candidates drawn from the good model per proposal, the most promising is proposed
+}
+
+func NewTPE(space *ParameterSpace, seed int64) *TPE {
+	return &TPE{
+		Space:         space,
+		Seed:          seed,
+		StartupTrials: 10,
+		Gamma:         0.25,
+		Candidates:    24,
+	}
+}
+
+// Propose returns at most batch combinations that are not in the trials, to evaluate in parallel.
+// The proposals only depend on the seed and the trials, so that a resumed study proposes the same combinations.
+// Pending trials, and the combinations of the batch, are assumed bad until evaluated, so that a batch spreads out.
+// Only combinations that satisfy the constraints are proposed.
+// It returns fewer combinations if the space is exhausted.
+func (t *TPE) Propose(trials []*Trial, batch int) ([]Combo, error) {
+	if t.Space == nil {
+		return nil, fmt.Errorf("no parameter space")
+	}
+
+	keys := t.Space.keys
+	rng := rand.New(rand.NewSource(t.Seed + int64(len(trials))))
+
+	seen := make(map[string]struct{}, len(trials))
+	var good, bad [][]int // Indices of the values of the trials
+
+	done := []*Trial{}
+	for _, trial := range trials {
+		seen[trial.Combo.ID()] = struct{}{}
+
+		if trial.Status == TrialStatusDone {
+			done = append(done, trial)
+		}
+	}
+
+	// Best trials first, ties by order of proposal
+	slices.SortStableFunc(done, func(a, b *Trial) int {
+		switch {
+		case a.Value > b.Value:
+			return -1
+		case a.Value < b.Value:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	goodCount := int(math.Ceil(t.Gamma * float64(len(done))))
+
+	isGood := make(map[*Trial]bool, goodCount)
+	for _, trial := range done[:goodCount] {
+		isGood[trial] = true
+	}
+
+	for _, trial := range trials {
+		indices, err := t.Space.indices(keys, trial.Combo)
+		if err != nil {
+			return nil, err
+		}
+
+		if isGood[trial] {
+			good = append(good, indices)
+		} else {
+			bad = append(bad, indices)
+		}
+	}
+
+	useModel := len(done) >= t.StartupTrials && goodCount > 0
+
+	combos := make([]Combo, 0, batch)
+	for len(combos) < batch && len(seen) < t.Space.Size() {
+		var combo Combo
+		var err error
+		if useModel {
+			if combo, err = t.proposeModel(rng, keys, good, bad, seen); err != nil {
+				return nil, err
+			}
+		}
+		if combo == nil {
+			if combo, err = t.proposeRandom(rng, keys, seen); err != nil {
+				return nil, err
+			}
+		}
+		if combo == nil {
+			break
+		}
+
+		seen[combo.ID()] = struct{}{}
+		combos = append(combos, combo)
+
+		// Constant liar: the next proposals of the batch avoid this one
+		indices, err := t.Space.indices(keys, combo)
+		if err != nil {
+			return nil, err
+		}
+		bad = append(bad, indices)
+	}
+
+	return combos, nil
+}
+
+// proposeModel returns the valid candidate with the best ratio of good to bad likelihood, nil if all candidates were seen or invalid.
+func (t *TPE) proposeModel(rng *rand.Rand, keys []string, good, bad [][]int, seen map[string]struct{}) (Combo, error) {
+	goodDensities := make([][]float64, len(keys))
+	badDensities := make([][]float64, len(keys))
+
+	for d, key := range keys {
+		values := t.Space.parameters[key].Values
+		ordinal := isOrdinal(values)
+		goodDensities[d] = parzenDensity(len(values), column(good, d), ordinal)
+		badDensities[d] = parzenDensity(len(values), column(bad, d), ordinal)
+	}
+
+	var best Combo
+	bestScore := math.Inf(-1)
+
+	for c := 0; c < t.Candidates; c++ {
+		combo := make(Combo, len(keys))
+		score := 0.0
+
+		for d, key := range keys {
+			index := sampleIndex(rng, goodDensities[d])
+			combo[key] = t.Space.parameters[key].Values[index]
+			score += math.Log(goodDensities[d][index]) - math.Log(badDensities[d][index])
+		}
+
+		if _, exists := seen[combo.ID()]; exists {
+			continue
+		}
+		valid, err := t.Space.isValid(combo)
+		if err != nil {
+			return nil, err
+		}
+		if valid && score > bestScore {
+			best, bestScore = combo, score
+		}
+	}
+
+	return best, nil
+}
+
+// proposeRandom returns a uniform random valid combination that was not seen, nil if none is found.
+func (t *TPE) proposeRandom(rng *rand.Rand, keys []string, seen map[string]struct{}) (Combo, error) {
+	for attempt := 0; attempt < 1000; attempt++ {
+		point := make([]float64, len(keys))
+		for d := range point {
+			point[d] = rng.Float64()
+		}
+
+		combo := t.Space.comboAt(keys, point)
+		if _, exists := seen[combo.ID()]; exists {
+			continue
+		}
+		valid, err := t.Space.isValid(combo)
+		if err != nil {
+			return nil, err
+		}
+		if valid {
+			return combo, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// indices returns the index of each value of a combination in the space.
+func (space *ParameterSpace) indices(keys []string, combo Combo) ([]int, error) {
+	indices := make([]int, len(keys))
+
+	for d, key := range keys {
+		value, ok := combo[key]
+		if !ok {
+			return nil, fmt.Errorf("parameter %s not in combination %s", key, combo.ID())
+		}
+
+		indices[d] = slices.IndexFunc(space.parameters[key].Values, func(v interface{}) bool { return v == value })
+		if indices[d] < 0 {
+			return nil, fmt.Errorf("value %v of parameter %s not in space", value, key)
+		}
+	}
+
+	return indices, nil
+}
+
+// parzenDensity estimates the probability of each of n values from observed indices.
+// Ordinal values (numbers) spread each observation to their neighbours, categorical values do not.
+// A uniform prior, weighing as much as one observation, keeps every value possible.
+func parzenDensity(n int, observed []int, ordinal bool) []float64 {
+	density := make([]float64, n)
+	for i := range density {
+		density[i] = 1 / float64(n)
+	}
+
+	bandwidth := math.Max(0.5, float64(n)/(1+math.Sqrt(float64(len(observed)))))
+
+	for _, o := range observed {
+		if !ordinal {
+			density[o]++
+			continue
+		}
+
+		kernel := make([]float64, n)
+		var total float64
+		for i := range kernel {
+			z := float64(i-o) / bandwidth
+			kernel[i] = math.Exp(-0.5 * z * z)
+			total += kernel[i]
+		}
+		for i := range kernel {
+			density[i] += kernel[i] / total
+		}
+	}
+
+	total := float64(1 + len(observed))
+	for i := range density {
+		density[i] /= total
+	}
+
+	return density
+}
+
+func sampleIndex(rng *rand.Rand, density []float64) int {
+	u := rng.Float64()
+	for i, p := range density {
+		u -= p
+		if u < 0 {
+			return i
+		}
+	}
+	return len(density) - 1
+}
+
+func column(rows [][]int, d int) []int {
+	values := make([]int, len(rows))
+	for i, row := range rows {
+		values[i] = row[d]
+	}
+	return values
+}
+
+// isOrdinal returns true if all values are numbers, assumed sorted in the space, so that close indices are close values.
+func isOrdinal(values []interface{}) bool {
+	for _, v := range values {
+		switch v.(type) {
+		case int, float64:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,271 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// SignificanceLevel is the level under which a p-value is considered significant.
+const SignificanceLevel = 0.05
+
+// BootstrapIterations is the number of resamples used to compute bootstrap confidence intervals.
+const BootstrapIterations = 1000
+
+// bootstrapSeed makes the bootstrap deterministic, so that the same trades always produce the same intervals.
+const bootstrapSeed = 42
+
+// Interval is a confidence interval.
+type Interval struct {
+	Low  float64
+	High float64
+}
+
+// Significance holds the statistical significance of a set of trades.
+type Significance struct {
+	// TStat is the t-statistic of the mean R-multiple against zero.
+	TStat float64
+
+	// PValue is the two-sided p-value associated with TStat.
+	PValue float64
+
+	// WinRateCI is the bootstrap confidence interval of the win rate (in percent).
+	WinRateCI Interval
+
+	// ExpectancyCI is the bootstrap confidence interval of the mean R-multiple.
+	ExpectancyCI Interval
+
+	// MinTrackRecordLength is the minimum number of trades needed for the observed
+	// mean R-multiple to be significant, given its skewness and kurtosis.
+	// It is +Inf when the mean R-multiple is not positive.
+	MinTrackRecordLength float64
+
+	// Significant is true when the mean R-multiple is distinguishable from zero.
+	Significant bool
+}
+
+// ComputeSignificance computes the significance statistics of a set of trades,
+// given the R-multiple of each trade and whether it was a winning trade.
+func ComputeSignificance(rMultiples []float64, wins []bool) Significance {
+	res := Significance{
+		PValue:               1,
+		MinTrackRecordLength: math.Inf(1),
+	}
+
+	if len(rMultiples) > 0 {
+		res.TStat, res.PValue = TTest(rMultiples)
+		res.ExpectancyCI = BootstrapMeanCI(rMultiples, 1-SignificanceLevel)
+		res.MinTrackRecordLength = MinTrackRecordLength(rMultiples, 1-SignificanceLevel)
+		res.Significant = res.PValue < SignificanceLevel
+	}
+
+	if len(wins) > 0 {
+		outcomes := make([]float64, len(wins))
+		for i, win := range wins {
+			if win {
+				outcomes[i] = 100
+			}
+		}
+		res.WinRateCI = BootstrapMeanCI(outcomes, 1-SignificanceLevel)
+	}
+
+	return res
+}
+
+// TTest computes the one-sample t-statistic of the mean of values against zero, and its two-sided p-value.
+// With less than 2 values or no variance, the result is not significant (t = 0, p = 1).
+func TTest(values []float64) (float64, float64) {
+	n := len(values)
+	if n < 2 {
+		return 0, 1
+	}
+
+	mean, stdev := meanStdev(values)
+	if stdev == 0 {
+		return 0, 1
+	}
+
+	t := mean / (stdev / math.Sqrt(float64(n)))
+	p := studentTwoSidedPValue(t, float64(n-1))
+	return t, p
+}
+
+// BootstrapMeanCI computes the percentile bootstrap confidence interval of the mean of values.
+func BootstrapMeanCI(values []float64, confidence float64) Interval {
+	n := len(values)
+	if n == 0 {
+		return Interval{}
+	}
+
+	rng := rand.New(rand.NewSource(bootstrapSeed))
+	means := make([]float64, BootstrapIterations)
+
+	for i := range means {
+		var sum float64
+		for j := 0; j < n; j++ {
+			sum += values[rng.Intn(n)]
+		}
+		means[i] = sum / float64(n)
+	}
+
+	slices.Sort(means)
+
+	alpha := (1 - confidence) / 2
+	return Interval{
+		Low:  percentile(means, alpha),
+		High: percentile(means, 1-alpha),
+	}
+}
+
+// MinTrackRecordLength computes the minimum number of trades needed for the observed
+// per-trade Sharpe ratio of values to be above zero with the given confidence
+// (Bailey & López de Prado, 2012).
+func MinTrackRecordLength(values []float64, confidence float64) float64 {
+	if len(values) < 2 {
+		return math.Inf(1)
+	}
+
+	mean, stdev := meanStdev(values)
+	if stdev == 0 || mean <= 0 {
+		return math.Inf(1)
+	}
+
+	sr := mean / stdev
+	skew, kurt := skewnessKurtosis(values, mean, stdev)
+	z := NormalQuantile(confidence)
+
+	return 1 + (1-skew*sr+(kurt-1)/4*sr*sr)*(z/sr)*(z/sr)
+}
+
+// NormalQuantile returns the quantile function (inverse CDF) of the standard normal distribution.
+func NormalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// NormalCDF returns the cumulative distribution function of the standard normal distribution.
+func NormalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+func meanStdev(values []float64) (float64, float64) {
+	n := float64(len(values))
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / n
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+
+	return mean, math.Sqrt(sq / (n - 1))
+}
+
+// skewnessKurtosis returns the sample skewness and (non-excess) kurtosis.
+func skewnessKurtosis(values []float64, mean, stdev float64) (float64, float64) {
+	n := float64(len(values))
+
+	var m3, m4 float64
+	for _, v := range values {
+		d := (v - mean) / stdev
+		m3 += d * d * d
+		m4 += d * d * d * d
+	}
+
+	return m3 / n, m4 / n
+}
+
+// percentile returns the p-th percentile of sorted values, with linear interpolation.
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+	return sorted[lower]*(1-frac) + sorted[upper]*frac
+}
+
+// studentTwoSidedPValue returns P(|T| >= |t|) for a Student t distribution with df degrees of freedom.
+func studentTwoSidedPValue(t, df float64) float64 {
+	x := df / (df + t*t)
+	return regularizedIncompleteBeta(df/2, 0.5, x)
+}
+
+// regularizedIncompleteBeta computes I_x(a, b) using its continued fraction representation.
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only for x < (a+1)/(a+b+2)
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const maxIterations = 200
+	const epsilon = 1e-14
+	const tiny = 1e-300
+
+	qab := a + b
+	qap := a + 1
+	qam := a - 1
+
+	c := 1.0
+	d := 1 - qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+
+		// Even step
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// Odd step
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+
+	return h
+}
@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"trading-bot/brokers/backtesting"
+	"trading-bot/common"
+	"trading-bot/traders"
+)
+
+// BatchPollInterval is the interval at which a batch checks whether its jobs are finished.
+const BatchPollInterval = time.Second
+
+// Batch submits backtests to a runner and waits until they are finished, run locally or by workers.
+type Batch struct {
+	runner *Runner
+	keys   []string // Jobs to wait for
+}
+
+func (r *Runner) NewBatch() *Batch {
+	return &Batch{runner: r}
+}
+
+// SubmitRun submits a backtest of a single month, see Runner.SubmitRun.
+func (b *Batch) SubmitRun(instrument string, month common.Month, strategy traders.Strategy, settings *Settings) error {
+	return b.SubmitRange(instrument, month, month, strategy, settings)
+}
+
+// SubmitRange submits a continuous backtest, see Runner.SubmitRange.
+func (b *Batch) SubmitRange(instrument string, from, to common.Month, strategy traders.Strategy, settings *Settings) error {
+	key, err := b.runner.submitRange(instrument, from, to, strategy, settings)
+	if err != nil {
+		return err
+	}
+
+	if key != "" {
+		b.keys = append(b.keys, key)
+	}
+	return nil
+}
+
+// Wait returns once all submitted jobs are done or failed, or ctx is canceled.
+func (b *Batch) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(BatchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		unfinished := b.keys[:0]
+		for _, key := range b.keys {
+			j, err := b.runner.db.FindJob(key)
+			if err != nil {
+				return fmt.Errorf("failed to find job: %w", err)
+			}
+
+			if j != nil && j.Status != JobStatusDone && j.Status != JobStatusFailed {
+				unfinished = append(unfinished, key)
+			}
+		}
+		b.keys = unfinished
+
+		if len(b.keys) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// StrategyStats aggregates the saved runs of a strategy of the current engine version, nil if there is none.
+// filter selects the runs (e.g., instrument, months, run ranges and settings), its strategy and engine version are ignored.
+// Without run ranges and settings, the months of overlapping runs, or of runs of different settings, are aggregated together.
+func (r *Runner) StrategyStats(strategy traders.Strategy, filter RunFilter) (*StrategyStats, error) {
+	filter.Strategy = strategy.Identity()
+	filter.EngineVersion = backtesting.EngineVersion
+
+	runs, err := r.db.FindRuns(&filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find runs: %w", err)
+	}
+
+	if len(runs) == 0 {
+		return nil, nil
+	}
+
+	return aggregateRuns(filter.Strategy, runs), nil
+}
@@ -3,9 +3,13 @@ package runner
 import (
 	"crypto/md5"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+	"trading-bot/brokers"
 	"trading-bot/brokers/backtesting"
+	"trading-bot/common"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -14,10 +18,19 @@ const dbFile = "output/data.db"
 
 type run struct {
 	// Config
-	Key        string // hash of next fields
-	Instrument string
-	TimeRange  string
-	Strategy   string
+	Key          string // hash of next fields
+	Instrument   string
+	TimeRange    string // Month of the results (e.g., 2023-01)
+	RunRange     string // Time range of the run that produced the month (e.g., 2023-01..2023-06), the month itself for a run of a single month
+	Strategy     string
+	StrategyKind string // e.g., modular, expression (not part of the key, as Strategy differs between kinds)
+	DataSource   string // part of the settings in the key
+	BrokerConfig string // Serialized broker config (JSON), part of the settings in the key
+	Settings     string // Serialized run settings (JSON), part of the key
+
+	// Engine
+	EngineVersion int    // Version of the backtesting engine that produced the results
+	Fingerprint   string // Engine version, broker config and data checksum, part of the key
 
 	// Results
 	backtesting.Metrics
@@ -33,29 +46,12 @@ func OpenDatabase() (*Database, error) {
 		return nil, err
 	}
 
-	// Create table if it doesn't exist
-	createTableSQL := `
-    CREATE TABLE IF NOT EXISTS runs (
-        -- Config
-        key TEXT PRIMARY KEY,                -- Unique hash for config
-        instrument TEXT NOT NULL,            -- e.g., EURUSD
-        time_range TEXT NOT NULL,             -- e.g., 202501
-        strategy TEXT NOT NULL,               -- Serialized strategy description (JSON)
-
-        -- Metrics
-        total_trades INTEGER NOT NULL,        -- Total trades
-        win_rate REAL NOT NULL,               -- % of winning trades
-        net_pnl REAL NOT NULL,                 -- Net profit/loss in base currency
-        profit_factor REAL NOT NULL,           -- Gross profit / gross loss
-        max_drawdown_pct REAL NOT NULL,        -- % from peak equity
-        expected_value_r REAL NOT NULL,        -- Avg R-multiple return
-        avg_trade_duration_seconds INTEGER NOT NULL, -- Duration in seconds
-        long_trades INTEGER NOT NULL,          -- Count of long trades
-        short_trades INTEGER NOT NULL          -- Count of short trades
-    );`
+	// SQLite supports a single writer, serialize accesses from the workers
+	db.SetMaxOpenConns(1)
 
-	if _, err := db.Exec(createTableSQL); err != nil {
-		return nil, err
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	return &Database{db}, nil
@@ -65,22 +61,25 @@ func (db *Database) Close() error {
 	return db.db.Close()
 }
 
-func (db *Database) ComputeKey(instrument, timeRange, strategy string) string {
+func (db *Database) ComputeKey(instrument, timeRange, strategy, settings, fingerprint string) string {
 	hash := md5.New()
-	hash.Write([]byte(fmt.Sprintf("%s:%s:%s", instrument, timeRange, strategy)))
+	hash.Write([]byte(fmt.Sprintf("%s:%s:%s:%s:%s", instrument, timeRange, strategy, settings, fingerprint)))
 	return fmt.Sprintf("%x", hash.Sum(nil))
 }
 
-// return nil if run does not exist
-func (db *Database) FindRun(instrument, timeRange, strategy string) (*run, error) {
-	key := db.ComputeKey(instrument, timeRange, strategy)
-
-	query := `
+const selectRunSQL = `
     SELECT
         key,
         instrument,
         time_range,
+        run_range,
         strategy,
+        strategy_kind,
+        data_source,
+        broker_config,
+        settings,
+        engine_version,
+        fingerprint,
         total_trades,
         win_rate,
         net_pnl,
@@ -89,20 +88,50 @@ func (db *Database) FindRun(instrument, timeRange, strategy string) (*run, error
         expected_value_r,
         avg_trade_duration_seconds,
         long_trades,
-        short_trades
-    FROM runs
-    WHERE key = ?;`
+        short_trades,
+        t_stat,
+        p_value,
+        win_rate_ci_low,
+        win_rate_ci_high,
+        expectancy_ci_low,
+        expectancy_ci_high,
+        min_track_record_length,
+        significant
+    FROM runs`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
+func scanRun(row rowScanner) (*run, error) {
 	var r run
 	var tradeDurationSeconds int64
 
-	err := db.db.QueryRow(query, key).Scan(
-		&r.Key, &r.Instrument, &r.TimeRange, &r.Strategy,
+	err := row.Scan(
+		&r.Key, &r.Instrument, &r.TimeRange, &r.RunRange, &r.Strategy, &r.StrategyKind,
+		&r.DataSource, &r.BrokerConfig, &r.Settings,
+		&r.EngineVersion, &r.Fingerprint,
 		&r.TotalTrades, &r.WinRate, &r.NetPnL,
 		&r.ProfitFactor, &r.MaxDrawdownPct,
 		&r.ExpectedValueR, &tradeDurationSeconds,
 		&r.LongTrades, &r.ShortTrades,
+		&r.TStat, &r.PValue,
+		&r.WinRateCI.Low, &r.WinRateCI.High,
+		&r.ExpectancyCI.Low, &r.ExpectancyCI.High,
+		&r.MinTrackRecordLength, &r.Significant,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	r.AvgTradeDuration = time.Second * time.Duration(tradeDurationSeconds)
+
+	return &r, nil
+}
+
+// return nil if run does not exist
+func (db *Database) FindRun(key string) (*run, error) {
+	r, err := scanRun(db.db.QueryRow(selectRunSQL+" WHERE key = ?;", key))
 
 	if err == sql.ErrNoRows {
 		return nil, nil // Run does not exist
@@ -110,35 +139,365 @@ func (db *Database) FindRun(instrument, timeRange, strategy string) (*run, error
 		return nil, err // Other error
 	}
 
-	r.AvgTradeDuration = time.Second * time.Duration(tradeDurationSeconds)
+	return r, nil
+}
 
-	return &r, nil
+// RunFilter selects runs in FindRuns. Zero-valued fields do not filter.
+type RunFilter struct {
+	Instrument      string
+	Instruments     []string // Only runs of these instruments, if not empty
+	TimeRange       string
+	From            string // First time range, inclusive (e.g., 2023-01)
+	To              string // Last time range, inclusive (e.g., 2023-12)
+	Strategy        string
+	StrategyKind    string
+	DataSource      string
+	Settings        string // Only runs of these settings (see Settings.Identity), if not empty
+	SignificantOnly bool   // Only runs whose mean R-multiple is distinguishable from zero
+	EngineVersion   int    // Only runs of this engine version, if not zero
+
+	// SingleMonths only selects the runs of a single month, and not the months of continuous runs.
+	SingleMonths bool
+
+	// RunRanges only selects the months produced by runs of these time ranges, e.g., 2023-01..2023-06 for a continuous run,
+	// or each month for runs of single months, so that the months of overlapping runs are not mixed.
+	RunRanges []string
+}
+
+// RunRanges returns the run ranges of the runs submitted from from to to, see RunFilter.RunRanges.
+func RunRanges(from, to common.Month, continuous bool) []string {
+	if continuous {
+		return []string{common.FormatMonthRange(from, to)}
+	}
+
+	var ranges []string
+	for _, month := range common.Months(from, to) {
+		ranges = append(ranges, month.String())
+	}
+	return ranges
+}
+
+func (db *Database) FindRuns(filter *RunFilter) ([]*run, error) {
+	query := selectRunSQL + " WHERE 1 = 1"
+	args := []any{}
+
+	if filter.Instrument != "" {
+		query += " AND instrument = ?"
+		args = append(args, filter.Instrument)
+	}
+	if len(filter.Instruments) > 0 {
+		query += " AND instrument IN (?" + strings.Repeat(", ?", len(filter.Instruments)-1) + ")"
+		for _, instrument := range filter.Instruments {
+			args = append(args, instrument)
+		}
+	}
+	if filter.TimeRange != "" {
+		query += " AND time_range = ?"
+		args = append(args, filter.TimeRange)
+	}
+	if filter.From != "" {
+		query += " AND time_range >= ?"
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		query += " AND time_range <= ?"
+		args = append(args, filter.To)
+	}
+	if filter.Strategy != "" {
+		query += " AND strategy = ?"
+		args = append(args, filter.Strategy)
+	}
+	if filter.StrategyKind != "" {
+		query += " AND strategy_kind = ?"
+		args = append(args, filter.StrategyKind)
+	}
+	if filter.DataSource != "" {
+		query += " AND data_source = ?"
+		args = append(args, filter.DataSource)
+	}
+	if filter.Settings != "" {
+		query += " AND settings = ?"
+		args = append(args, filter.Settings)
+	}
+	if filter.SignificantOnly {
+		query += " AND significant = 1"
+	}
+	if filter.EngineVersion != 0 {
+		query += " AND engine_version = ?"
+		args = append(args, filter.EngineVersion)
+	}
+	if filter.SingleMonths {
+		query += " AND run_range = time_range"
+	}
+	if len(filter.RunRanges) > 0 {
+		query += " AND run_range IN (?" + strings.Repeat(", ?", len(filter.RunRanges)-1) + ")"
+		for _, runRange := range filter.RunRanges {
+			args = append(args, runRange)
+		}
+	}
+
+	rows, err := db.db.Query(query+";", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []*run{}
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+
+	return runs, rows.Err()
+}
+
+// SaveRuns saves runs and their trades (trades[i] are the trades of runs[i]) at once.
+func (db *Database) SaveRuns(runs []*run, trades [][]*backtesting.Trade) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := saveRuns(tx, runs, trades); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func saveRuns(tx *sql.Tx, runs []*run, trades [][]*backtesting.Trade) error {
+	for i, r := range runs {
+		if err := saveRun(tx, r, trades[i]); err != nil {
+			return fmt.Errorf("failed to save run of %s: %w", r.TimeRange, err)
+		}
+	}
+
+	return nil
 }
 
-func (db *Database) SaveRun(r *run) error {
-	key := db.ComputeKey(r.Instrument, r.TimeRange, r.Strategy)
+// saveRun saves the run and its trades.
+// r.Key must be computed from the run config, including its settings.
+func saveRun(tx *sql.Tx, r *run, trades []*backtesting.Trade) error {
+	key := r.Key
 	tradeDurationSeconds := int64(r.AvgTradeDuration.Seconds())
 
 	// Insert or update the run
 	query := `
     INSERT INTO runs (
-        key, instrument, time_range, strategy,
+        key, instrument, time_range, run_range, strategy, strategy_kind,
+        data_source, broker_config, settings,
+        engine_version, fingerprint,
         total_trades, win_rate, net_pnl,
         profit_factor, max_drawdown_pct,
         expected_value_r, avg_trade_duration_seconds,
-        long_trades, short_trades
-    ) VALUES (?, ?, ?, ?,
+        long_trades, short_trades,
+        t_stat, p_value,
+        win_rate_ci_low, win_rate_ci_high,
+        expectancy_ci_low, expectancy_ci_high,
+        min_track_record_length, significant
+    ) VALUES (?, ?, ?, ?, ?, ?,
         ?, ?, ?,
+        ?, ?,
         ?, ?, ?,
-        ?, ?, ?
+        ?, ?, ?,
+        ?, ?, ?,
+        ?, ?,
+        ?, ?,
+        ?, ?,
+        ?, ?
     );`
 
-	_, err := db.db.Exec(query, key, r.Instrument, r.TimeRange, r.Strategy,
+	_, err := tx.Exec(query, key, r.Instrument, r.TimeRange, r.RunRange, r.Strategy, r.StrategyKind,
+		r.DataSource, r.BrokerConfig, r.Settings,
+		r.EngineVersion, r.Fingerprint,
 		r.TotalTrades, r.WinRate, r.NetPnL,
 		r.ProfitFactor, r.MaxDrawdownPct,
 		r.ExpectedValueR, tradeDurationSeconds,
 		r.LongTrades, r.ShortTrades,
+		r.TStat, r.PValue,
+		r.WinRateCI.Low, r.WinRateCI.High,
+		r.ExpectancyCI.Low, r.ExpectancyCI.High,
+		r.MinTrackRecordLength, r.Significant,
 	)
+	if err != nil {
+		return err
+	}
+
+	return saveTrades(tx, key, trades)
+}
+
+func saveTrades(tx *sql.Tx, runKey string, trades []*backtesting.Trade) error {
+	stmt, err := tx.Prepare(`
+    INSERT INTO trades (
+        run_key, trade_index,
+        direction, open_time, close_time,
+        open_price, close_price,
+        stop_loss, take_profit,
+        quantity, pnl, r_multiple,
+        reason, reason_details, close_reason
+    ) VALUES (?, ?,
+        ?, ?, ?,
+        ?, ?,
+        ?, ?,
+        ?, ?, ?,
+        ?, ?, ?
+    );`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for index, trade := range trades {
+		var reasonDetails sql.NullString
+		if trade.ReasonDetails != nil {
+			data, err := json.Marshal(trade.ReasonDetails)
+			if err != nil {
+				return fmt.Errorf("failed to serialize trade reason: %w", err)
+			}
+			reasonDetails = sql.NullString{String: string(data), Valid: true}
+		}
+
+		_, err := stmt.Exec(runKey, index,
+			trade.Direction.String(), trade.OpenTime.UnixMilli(), trade.CloseTime.UnixMilli(),
+			trade.OpenPrice, trade.ClosePrice,
+			trade.StopLoss, trade.TakeProfit,
+			trade.Quantity, trade.PnL, trade.RMultiple,
+			trade.Reason, reasonDetails, trade.CloseReason,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindTrades returns the trades of a run, in order.
+func (db *Database) FindTrades(runKey string) ([]*backtesting.Trade, error) {
+	rows, err := db.db.Query(`
+    SELECT
+        direction, open_time, close_time,
+        open_price, close_price,
+        stop_loss, take_profit,
+        quantity, pnl, r_multiple,
+        reason, reason_details, close_reason
+    FROM trades
+    WHERE run_key = ?
+    ORDER BY trade_index;`, runKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trades := []*backtesting.Trade{}
+	for rows.Next() {
+		var trade backtesting.Trade
+		var direction string
+		var openTime, closeTime int64
+		var reasonDetails sql.NullString
+
+		err := rows.Scan(
+			&direction, &openTime, &closeTime,
+			&trade.OpenPrice, &trade.ClosePrice,
+			&trade.StopLoss, &trade.TakeProfit,
+			&trade.Quantity, &trade.PnL, &trade.RMultiple,
+			&trade.Reason, &reasonDetails, &trade.CloseReason,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		switch direction {
+		case brokers.PositionDirectionLong.String():
+			trade.Direction = brokers.PositionDirectionLong
+		case brokers.PositionDirectionShort.String():
+			trade.Direction = brokers.PositionDirectionShort
+		default:
+			return nil, fmt.Errorf("invalid trade direction: %s", direction)
+		}
+
+		trade.OpenTime = time.UnixMilli(openTime).UTC()
+		trade.CloseTime = time.UnixMilli(closeTime).UTC()
+
+		if reasonDetails.Valid {
+			trade.ReasonDetails = &brokers.OrderReason{}
+			if err := json.Unmarshal([]byte(reasonDetails.String), trade.ReasonDetails); err != nil {
+				return nil, fmt.Errorf("failed to parse trade reason: %w", err)
+			}
+		}
+
+		trades = append(trades, &trade)
+	}
+
+	return trades, rows.Err()
+}
+
+// EngineVersionCount is the number of runs produced by an engine version.
+type EngineVersionCount struct {
+	EngineVersion int
+	Runs          int
+	Trades        int
+}
+
+// CountRunsByEngineVersion returns the number of runs per engine version, most recent version first.
+func (db *Database) CountRunsByEngineVersion() ([]*EngineVersionCount, error) {
+	rows, err := db.db.Query(`
+    SELECT
+        engine_version,
+        COUNT(*),
+        SUM(total_trades)
+    FROM runs
+    GROUP BY engine_version
+    ORDER BY engine_version DESC;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []*EngineVersionCount{}
+	for rows.Next() {
+		var c EngineVersionCount
+		if err := rows.Scan(&c.EngineVersion, &c.Runs, &c.Trades); err != nil {
+			return nil, err
+		}
+		counts = append(counts, &c)
+	}
+
+	return counts, rows.Err()
+}
+
+// PurgeRuns deletes the runs produced by engine versions older than version, with their trades and jobs.
+// It returns the number of deleted runs.
+func (db *Database) PurgeRuns(version int) (int, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM trades WHERE run_key IN (SELECT key FROM runs WHERE engine_version < ?);`, version)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM jobs WHERE engine_version < ?;`, version)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec(`DELETE FROM runs WHERE engine_version < ?;`, version)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
 
-	return err
+	return int(count), tx.Commit()
 }
@@ -1,39 +1,129 @@
 package runner
 
 import (
+	"container/list"
 	"fmt"
 	"sync"
 	"trading-bot/brokers/backtesting"
 	"trading-bot/common"
 )
 
+// DefaultDatasetBudget is the default memory budget of the dataset cache, in bytes.
+const DefaultDatasetBudget = 4 << 30 // 4 GiB
+
+// datasets is an LRU cache of datasets, bounded by a memory budget.
+// Different datasets are loaded concurrently, and a dataset requested while loading is loaded once.
+// Datasets in use are never evicted, so the budget may be exceeded while they are running.
 type datasets struct {
-	datasets map[string]*backtesting.Dataset
-	lock     sync.Mutex
+	lock    sync.Mutex
+	entries map[string]*datasetEntry
+	lru     *list.List // Of *datasetEntry, most recently used first
+	size    int64      // Memory size of the loaded datasets
+	budget  int64
+}
+
+type datasetEntry struct {
+	key     string
+	dataset *backtesting.Dataset
+	err     error
+	loaded  chan struct{} // Closed once loaded
+	refs    int           // Number of users of the dataset
+	element *list.Element // nil while loading
 }
 
-func newDatasets() *datasets {
+func newDatasets(budget int64) *datasets {
 	return &datasets{
-		datasets: make(map[string]*backtesting.Dataset),
-		lock:     sync.Mutex{},
+		entries: make(map[string]*datasetEntry),
+		lru:     list.New(),
+		budget:  budget,
 	}
 }
 
-func (d *datasets) Get(instrument string, month common.Month) (*backtesting.Dataset, error) {
+func datasetKey(dataSource backtesting.DataSource, instrument string, from, to common.Month) string {
+	return fmt.Sprintf("%s-%s-%s", dataSource, instrument, common.FormatMonthRange(from, to))
+}
+
+// SetBudget changes the memory budget, in bytes, evicting datasets if needed.
+func (d *datasets) SetBudget(budget int64) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	key := fmt.Sprintf("%s-%s", instrument, month.String())
+	d.budget = budget
+	d.evict()
+}
+
+// Get returns the dataset of a range of months, loading it if needed, and a function to call once the dataset is no longer used.
+func (d *datasets) Get(dataSource backtesting.DataSource, instrument string, from, to common.Month) (*backtesting.Dataset, func(), error) {
+	key := datasetKey(dataSource, instrument, from, to)
 
-	if dataset, exists := d.datasets[key]; exists {
-		return dataset, nil
+	d.lock.Lock()
+	entry, exists := d.entries[key]
+	if !exists {
+		entry = &datasetEntry{key: key, loaded: make(chan struct{})}
+		d.entries[key] = entry
 	}
+	entry.refs++
+	if entry.element != nil {
+		d.lru.MoveToFront(entry.element)
+	}
+	d.lock.Unlock()
+
+	if exists {
+		<-entry.loaded
+	} else {
+		d.load(entry, dataSource, instrument, from, to)
+	}
+
+	if entry.err != nil {
+		d.release(entry)
+		return nil, nil, entry.err
+	}
+
+	return entry.dataset, func() { d.release(entry) }, nil
+}
+
+func (d *datasets) load(entry *datasetEntry, dataSource backtesting.DataSource, instrument string, from, to common.Month) {
+	dataset, err := backtesting.LoadDataset(dataSource, from, to, instrument)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	entry.dataset, entry.err = dataset, err
+	close(entry.loaded)
 
-	dataset, err := backtesting.LoadDataset(month, month, instrument)
 	if err != nil {
-		return nil, err
+		// Not cached, so that the next request tries again
+		delete(d.entries, entry.key)
+		return
 	}
 
-	d.datasets[key] = dataset
-	return dataset, nil
+	entry.element = d.lru.PushFront(entry)
+	d.size += dataset.MemorySize()
+	d.evict()
+}
+
+func (d *datasets) release(entry *datasetEntry) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	entry.refs--
+	d.evict()
+}
+
+// evict removes the least recently used datasets that are not in use, until the size fits the budget.
+// The lock must be held.
+func (d *datasets) evict() {
+	for element := d.lru.Back(); element != nil && d.size > d.budget; {
+		entry := element.Value.(*datasetEntry)
+		previous := element.Prev()
+
+		if entry.refs == 0 {
+			log.Debug("Evicting dataset %s", entry.key)
+			d.lru.Remove(element)
+			delete(d.entries, entry.key)
+			d.size -= entry.dataset.MemorySize()
+		}
+
+		element = previous
+	}
 }
@@ -0,0 +1,423 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+	"trading-bot/brokers/backtesting"
+	"trading-bot/common"
+	"trading-bot/traders"
+)
+
+// LeaseTimeout is the duration after which a job whose worker stopped renewing its lease is reassigned.
+const LeaseTimeout = 2 * time.Minute
+
+// WorkerPollInterval is the interval at which idle workers ask for a job.
+const WorkerPollInterval = 2 * time.Second
+
+// Messages between the coordinator and the workers are encoded with gob rather than JSON,
+// as metrics may hold infinite values (e.g., MinTrackRecordLength).
+const gobContentType = "application/x-gob"
+
+type leaseRequest struct {
+	Worker string
+}
+
+type leaseResponse struct {
+	Job          *job // nil if there is no job to run
+	LeaseTimeout time.Duration
+}
+
+type renewRequest struct {
+	Worker string
+	Key    string
+}
+
+type completeRequest struct {
+	Worker  string
+	Key     string
+	Error   string // empty on success
+	Results []*monthResult
+}
+
+// Coordinator serves the jobs submitted to it to workers over HTTP, other jobs of the database are left alone:
+//   - POST /lease: assigns a job to a worker
+//   - POST /renew: extends the lease of a job
+//   - POST /complete: saves the result of a job
+//   - GET /status: number of jobs per status (JSON)
+type Coordinator struct {
+	ctx        context.Context
+	db         *Database
+	submission string // Identifies the jobs submitted to the coordinator
+	server     *http.Server
+	wg         sync.WaitGroup
+}
+
+func NewCoordinator(ctx context.Context, db *Database) *Coordinator {
+	c := &Coordinator{
+		ctx:        ctx,
+		db:         db,
+		submission: fmt.Sprintf("%x", time.Now().UnixNano()),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /lease", c.handleLease)
+	mux.HandleFunc("POST /renew", c.handleRenew)
+	mux.HandleFunc("POST /complete", c.handleComplete)
+	mux.HandleFunc("GET /status", c.handleStatus)
+
+	c.server = &http.Server{Handler: mux}
+
+	return c
+}
+
+// Serve serves the jobs on a local HTTP address (e.g., localhost:8082).
+func (c *Coordinator) Serve(addr string) {
+	c.server.Addr = addr
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		log.Info("Coordinating workers on http://%s/", addr)
+		if err := c.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Failed to serve jobs: %v", err)
+		}
+	}()
+}
+
+// Close waits until all submitted jobs are finished, and stops serving.
+// On cancellation, it only waits for leased jobs, at most LeaseTimeout.
+func (c *Coordinator) Close() {
+	ticker := time.NewTicker(WorkerPollInterval)
+	defer ticker.Stop()
+
+	lastReport := time.Now()
+	var deadline time.Time // set on cancellation
+
+	for range ticker.C {
+		counts, err := c.db.CountJobs(c.submission)
+		if err != nil {
+			log.Error("Failed to count jobs: %v", err)
+			continue
+		}
+
+		if c.ctx.Err() != nil {
+			if deadline.IsZero() {
+				log.Info("Canceled, waiting for %d running jobs", counts[JobStatusRunning])
+				deadline = time.Now().Add(LeaseTimeout)
+			}
+			if counts[JobStatusRunning] == 0 || time.Now().After(deadline) {
+				break
+			}
+		} else if counts[JobStatusPending] == 0 && counts[JobStatusRunning] == 0 {
+			break
+		}
+
+		if time.Since(lastReport) >= ProgressReportInterval {
+			log.Info("⏳ Progress: %s", formatJobCounts(counts))
+			lastReport = time.Now()
+		}
+	}
+
+	if err := c.server.Shutdown(context.Background()); err != nil {
+		log.Error("Failed to stop serving jobs: %v", err)
+	}
+	c.wg.Wait()
+
+	counts, err := c.db.CountJobs(c.submission)
+	if err != nil {
+		log.Error("Failed to count jobs: %v", err)
+		return
+	}
+	log.Info("🏁 Finished: %s", formatJobCounts(counts))
+}
+
+func formatJobCounts(counts map[JobStatus]int) string {
+	return fmt.Sprintf("%d pending, %d running, %d done, %d failed",
+		counts[JobStatusPending], counts[JobStatusRunning], counts[JobStatusDone], counts[JobStatusFailed])
+}
+
+func (c *Coordinator) handleLease(w http.ResponseWriter, req *http.Request) {
+	var request leaseRequest
+	if err := gob.NewDecoder(req.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := leaseResponse{LeaseTimeout: LeaseTimeout}
+
+	// Stop assigning jobs on cancellation, leased ones are still completed
+	if c.ctx.Err() == nil {
+		j, err := c.db.LeaseJob(c.submission, request.Worker, LeaseTimeout)
+		if err != nil {
+			log.Error("Failed to lease job: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		res.Job = j
+	}
+
+	writeGob(w, &res)
+}
+
+func (c *Coordinator) handleRenew(w http.ResponseWriter, req *http.Request) {
+	var request renewRequest
+	if err := gob.NewDecoder(req.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	leased, err := c.db.RenewLease(request.Key, request.Worker, LeaseTimeout)
+	if err != nil {
+		log.Error("Failed to renew lease of job %s: %v", request.Key, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !leased {
+		http.Error(w, "job is not leased to this worker", http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Coordinator) handleComplete(w http.ResponseWriter, req *http.Request) {
+	var request completeRequest
+	if err := gob.NewDecoder(req.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	leased, err := c.complete(&request)
+	if err != nil {
+		log.Error("Failed to complete job %s: %v", request.Key, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !leased {
+		http.Error(w, "job is not leased to this worker", http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Coordinator) complete(request *completeRequest) (bool, error) {
+	if request.Error != "" {
+		log.Error("Worker '%s' failed job %s: %s", request.Worker, request.Key, request.Error)
+		return c.db.CompleteLeasedJob(request.Key, request.Worker, nil, nil, errors.New(request.Error))
+	}
+
+	if len(request.Results) == 0 {
+		return false, fmt.Errorf("missing results")
+	}
+
+	j, err := scanJob(c.db.db.QueryRow(selectJobSQL+" WHERE key = ?;", request.Key))
+	if err != nil {
+		return false, fmt.Errorf("failed to find job: %w", err)
+	}
+
+	var settings Settings
+	if err := json.Unmarshal([]byte(j.Settings), &settings); err != nil {
+		return false, fmt.Errorf("invalid settings: %w", err)
+	}
+
+	runs, trades := newRuns(j, &settings, request.Results)
+
+	return c.db.CompleteLeasedJob(request.Key, request.Worker, runs, trades, nil)
+}
+
+func (c *Coordinator) handleStatus(w http.ResponseWriter, req *http.Request) {
+	counts, err := c.db.CountJobs(c.submission)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(counts); err != nil {
+		log.Error("Failed to write status: %v", err)
+	}
+}
+
+func writeGob(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", gobContentType)
+	if err := gob.NewEncoder(w).Encode(v); err != nil {
+		log.Error("Failed to write response: %v", err)
+	}
+}
+
+// Worker runs the jobs of a coordinator, loading datasets locally.
+type Worker struct {
+	coordinatorURL string
+	name           string
+	datasets       *datasets
+	fingerprints   *fingerprints
+	client         *http.Client
+}
+
+// NewWorker creates a worker for the coordinator at the given URL (e.g., http://localhost:8082).
+func NewWorker(coordinatorURL string) *Worker {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return &Worker{
+		coordinatorURL: coordinatorURL,
+		name:           fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		datasets:       newDatasets(DefaultDatasetBudget),
+		fingerprints:   newFingerprints(),
+		client:         &http.Client{Timeout: time.Minute},
+	}
+}
+
+// SetDatasetBudget changes the memory budget of the dataset cache, in bytes.
+func (w *Worker) SetDatasetBudget(budget int64) {
+	w.datasets.SetBudget(budget)
+}
+
+// Run runs jobs on concurrency goroutines until ctx is canceled.
+// On cancellation, running jobs finish normally and their results are pushed.
+func (w *Worker) Run(ctx context.Context, concurrency int) {
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		go func(id int) {
+			defer wg.Done()
+			w.loop(ctx, fmt.Sprintf("%s-%d", w.name, id))
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context, name string) {
+	for ctx.Err() == nil {
+		var res leaseResponse
+		if err := w.post("/lease", &leaseRequest{Worker: name}, &res); err != nil {
+			log.Warning("Failed to lease job: %v", err)
+			sleep(ctx, WorkerPollInterval)
+			continue
+		}
+
+		if res.Job == nil {
+			sleep(ctx, WorkerPollInterval)
+			continue
+		}
+
+		w.process(name, res.Job, res.LeaseTimeout)
+	}
+}
+
+func (w *Worker) process(name string, j *job, leaseTimeout time.Duration) {
+	// Renew the lease while the job runs
+	stopRenew := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(leaseTimeout / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := w.post("/renew", &renewRequest{Worker: name, Key: j.Key}, nil); err != nil {
+					log.Warning("Failed to renew lease of job %s: %v", j.Key, err)
+				}
+			case <-stopRenew:
+				return
+			}
+		}
+	}()
+
+	results, err := w.run(j)
+	close(stopRenew)
+
+	request := &completeRequest{
+		Worker:  name,
+		Key:     j.Key,
+		Results: results,
+	}
+	if err != nil {
+		log.Error("Failed to run strategy for %s %s: %v", j.Instrument, j.TimeRange, err)
+		request.Error = err.Error()
+	}
+
+	if err := w.post("/complete", request, nil); err != nil {
+		log.Error("Failed to complete job %s: %v", j.Key, err)
+		return
+	}
+
+	if err == nil {
+		log.Info("Run completed for %s %s", j.Instrument, j.TimeRange)
+	}
+}
+
+func (w *Worker) run(j *job) ([]*monthResult, error) {
+	from, to, err := common.ParseMonthRange(j.TimeRange)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, err := traders.StrategyFromIdentity(traders.StrategyKind(j.StrategyKind), j.Strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings Settings
+	if err := json.Unmarshal([]byte(j.Settings), &settings); err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+
+	// Results must not depend on the worker: same engine version and data as the coordinator
+	warmUpFrom, _ := dataRange(from, to, &settings)
+	fingerprint, err := w.fingerprints.Get(j.Instrument, warmUpFrom, to, &settings)
+	if err != nil {
+		return nil, err
+	}
+	if fingerprint != j.Fingerprint {
+		return nil, fmt.Errorf("engine fingerprint mismatch (worker engine version %d, job engine version %d), check versions and data files", backtesting.EngineVersion, j.EngineVersion)
+	}
+
+	return backtest(w.datasets, j.Instrument, from, to, strategy, &settings)
+}
+
+// post sends a request to the coordinator and decodes its response into res, if not nil.
+func (w *Worker) post(path string, request any, res any) error {
+	var body bytes.Buffer
+	if err := gob.NewEncoder(&body).Encode(request); err != nil {
+		return err
+	}
+
+	resp, err := w.client.Post(w.coordinatorURL+path, gobContentType, &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var message bytes.Buffer
+		message.ReadFrom(resp.Body)
+		return fmt.Errorf("coordinator returned %s: %s", resp.Status, bytes.TrimSpace(message.Bytes()))
+	}
+
+	if res != nil {
+		return gob.NewDecoder(resp.Body).Decode(res)
+	}
+
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
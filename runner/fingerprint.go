@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"sync"
+	"trading-bot/brokers/backtesting"
+	"trading-bot/common"
+)
+
+// fingerprints computes the engine fingerprints of runs: the engine version, the broker config
+// and the checksum of the data, so that results are not reused once one of them changes.
+type fingerprints struct {
+	lock      sync.Mutex
+	checksums map[string]string // Data checksums by dataset key
+}
+
+func newFingerprints() *fingerprints {
+	return &fingerprints{
+		checksums: make(map[string]string),
+	}
+}
+
+// Get returns the fingerprint of a run on the data of a range of months, including its warm-up.
+func (f *fingerprints) Get(instrument string, from, to common.Month, settings *Settings) (string, error) {
+	checksums := make([]string, 0)
+	for _, month := range common.Months(from, to) {
+		checksum, err := f.checksum(settings.DataSource, instrument, month)
+		if err != nil {
+			return "", fmt.Errorf("failed to compute data checksum for %s %s: %w", instrument, month.String(), err)
+		}
+		checksums = append(checksums, checksum)
+	}
+	checksum := strings.Join(checksums, ",")
+
+	hash := md5.New()
+	hash.Write([]byte(fmt.Sprintf("%d:%s:%s", backtesting.EngineVersion, settings.brokerConfigJSON(), checksum)))
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+func (f *fingerprints) checksum(dataSource backtesting.DataSource, instrument string, month common.Month) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	key := datasetKey(dataSource, instrument, month, month)
+	if checksum, exists := f.checksums[key]; exists {
+		return checksum, nil
+	}
+
+	checksum, err := backtesting.DataChecksum(dataSource, month, instrument)
+	if err != nil {
+		return "", err
+	}
+
+	f.checksums[key] = checksum
+	return checksum, nil
+}
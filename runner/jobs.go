@@ -0,0 +1,357 @@
+package runner
+
+import (
+	"database/sql"
+	"time"
+	"trading-bot/brokers/backtesting"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending JobStatus = "pending"
+	JobStatusRunning JobStatus = "running"
+	JobStatusDone    JobStatus = "done"
+	JobStatusFailed  JobStatus = "failed"
+)
+
+type job struct {
+	Key          string // Key of the run
+	Instrument   string
+	TimeRange    string
+	Strategy     string
+	StrategyKind string
+	Settings     string
+
+	EngineVersion int
+	Fingerprint   string
+
+	Status      JobStatus
+	Error       string
+	Attempts    int
+	SubmittedAt time.Time
+	StartedAt   time.Time // zero if never started
+	FinishedAt  time.Time // zero if never finished
+
+	Worker         string    // empty when run locally
+	LeaseExpiresAt time.Time // zero when run locally
+	Submission     string    // Submission of the coordinator serving the job, empty when run locally
+}
+
+const selectJobSQL = `
+    SELECT
+        key, instrument, time_range, strategy, strategy_kind, settings,
+        engine_version, fingerprint,
+        status, error, attempts, submitted_at, started_at, finished_at,
+        worker, lease_expires_at, submission
+    FROM jobs`
+
+func scanJob(row rowScanner) (*job, error) {
+	var j job
+	var submittedAt int64
+	var startedAt, finishedAt, leaseExpiresAt sql.NullInt64
+
+	err := row.Scan(
+		&j.Key, &j.Instrument, &j.TimeRange, &j.Strategy, &j.StrategyKind, &j.Settings,
+		&j.EngineVersion, &j.Fingerprint,
+		&j.Status, &j.Error, &j.Attempts, &submittedAt, &startedAt, &finishedAt,
+		&j.Worker, &leaseExpiresAt, &j.Submission,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	j.SubmittedAt = time.UnixMilli(submittedAt)
+	if startedAt.Valid {
+		j.StartedAt = time.UnixMilli(startedAt.Int64)
+	}
+	if finishedAt.Valid {
+		j.FinishedAt = time.UnixMilli(finishedAt.Int64)
+	}
+	if leaseExpiresAt.Valid {
+		j.LeaseExpiresAt = time.UnixMilli(leaseExpiresAt.Int64)
+	}
+
+	return &j, nil
+}
+
+// SaveJob records a submitted job as pending.
+// A job submitted again keeps its attempts count and error.
+func (db *Database) SaveJob(j *job) error {
+	_, err := db.db.Exec(`
+    INSERT INTO jobs (
+        key, instrument, time_range, strategy, strategy_kind, settings,
+        engine_version, fingerprint,
+        status, submitted_at, submission
+    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT (key) DO UPDATE SET
+        status = excluded.status,
+        submitted_at = excluded.submitted_at,
+        submission = excluded.submission;`,
+		j.Key, j.Instrument, j.TimeRange, j.Strategy, j.StrategyKind, j.Settings,
+		j.EngineVersion, j.Fingerprint,
+		JobStatusPending, time.Now().UnixMilli(), j.Submission,
+	)
+
+	return err
+}
+
+// AdoptJob moves a pending or running job to a submission, so that its coordinator serves it.
+func (db *Database) AdoptJob(key, submission string) error {
+	_, err := db.db.Exec(`UPDATE jobs SET submission = ? WHERE key = ?;`, submission, key)
+	return err
+}
+
+func (db *Database) StartJob(key string) error {
+	_, err := db.db.Exec(`
+    UPDATE jobs SET
+        status = ?,
+        attempts = attempts + 1,
+        started_at = ?,
+        finished_at = NULL,
+        worker = '',
+        lease_expires_at = NULL
+    WHERE key = ?;`,
+		JobStatusRunning, time.Now().UnixMilli(), key,
+	)
+
+	return err
+}
+
+// FinishJob records the outcome of a job, failed if jobErr is not nil.
+func (db *Database) FinishJob(key string, jobErr error) error {
+	status := JobStatusDone
+	errorText := ""
+	if jobErr != nil {
+		status = JobStatusFailed
+		errorText = jobErr.Error()
+	}
+
+	_, err := db.db.Exec(`
+    UPDATE jobs SET
+        status = ?,
+        error = ?,
+        finished_at = ?
+    WHERE key = ?;`,
+		status, errorText, time.Now().UnixMilli(), key,
+	)
+
+	return err
+}
+
+// FindJob returns nil if the job does not exist.
+func (db *Database) FindJob(key string) (*job, error) {
+	j, err := scanJob(db.db.QueryRow(selectJobSQL+" WHERE key = ?;", key))
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (db *Database) DeleteJob(key string) error {
+	_, err := db.db.Exec(`DELETE FROM jobs WHERE key = ?;`, key)
+	return err
+}
+
+// FindUnfinishedJobs returns pending and failed jobs, and jobs left running by a process that stopped.
+func (db *Database) FindUnfinishedJobs() ([]*job, error) {
+	rows, err := db.db.Query(selectJobSQL+`
+    WHERE status IN (?, ?, ?)
+    ORDER BY submitted_at;`,
+		JobStatusPending, JobStatusRunning, JobStatusFailed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+
+	return jobs, rows.Err()
+}
+
+// LeaseJob assigns a pending job of the submission, or a running one whose lease expired, to a worker.
+// Jobs are ordered by dataset (instrument and month), so that workers load each dataset as few times as possible.
+// It returns nil if there is no job to run.
+func (db *Database) LeaseJob(submission, worker string, timeout time.Duration) (*job, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+
+	j, err := scanJob(tx.QueryRow(selectJobSQL+`
+    WHERE submission = ? AND (status = ?
+        OR (status = ? AND (lease_expires_at IS NULL OR lease_expires_at < ?)))
+    ORDER BY instrument, time_range, submitted_at
+    LIMIT 1;`,
+		submission, JobStatusPending, JobStatusRunning, now.UnixMilli(),
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil // No job to run
+	} else if err != nil {
+		return nil, err
+	}
+
+	if j.Status == JobStatusRunning {
+		log.Warning("Reassigning job %s of worker '%s' to worker '%s'", j.Key, j.Worker, worker)
+	}
+
+	j.Status = JobStatusRunning
+	j.Attempts++
+	j.StartedAt = now
+	j.FinishedAt = time.Time{}
+	j.Worker = worker
+	j.LeaseExpiresAt = now.Add(timeout)
+
+	_, err = tx.Exec(`
+    UPDATE jobs SET
+        status = ?,
+        attempts = ?,
+        started_at = ?,
+        finished_at = NULL,
+        worker = ?,
+        lease_expires_at = ?
+    WHERE key = ?;`,
+		j.Status, j.Attempts, j.StartedAt.UnixMilli(), j.Worker, j.LeaseExpiresAt.UnixMilli(), j.Key,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return j, tx.Commit()
+}
+
+// RenewLease extends the lease of a job. It returns false if the job is no longer leased to the worker.
+func (db *Database) RenewLease(key, worker string, timeout time.Duration) (bool, error) {
+	res, err := db.db.Exec(`
+    UPDATE jobs SET
+        lease_expires_at = ?
+    WHERE key = ? AND worker = ? AND status = ?;`,
+		time.Now().Add(timeout).UnixMilli(), key, worker, JobStatusRunning,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	count, err := res.RowsAffected()
+	return count == 1, err
+}
+
+// CompleteLeasedJob saves the results of a job run by a worker (see SaveRuns), or its error if jobErr is not nil.
+// It returns false, without saving anything, if the job is no longer leased to the worker.
+func (db *Database) CompleteLeasedJob(key, worker string, runs []*run, trades [][]*backtesting.Trade, jobErr error) (bool, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM jobs WHERE key = ? AND worker = ? AND status = ?;`,
+		key, worker, JobStatusRunning,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	if count == 0 {
+		return false, nil
+	}
+
+	status := JobStatusDone
+	errorText := ""
+	if jobErr != nil {
+		status = JobStatusFailed
+		errorText = jobErr.Error()
+	} else if err := saveRuns(tx, runs, trades); err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(`
+    UPDATE jobs SET
+        status = ?,
+        error = ?,
+        finished_at = ?,
+        lease_expires_at = NULL
+    WHERE key = ?;`,
+		status, errorText, time.Now().UnixMilli(), key,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// CountJobs returns the number of jobs of a submission per status.
+func (db *Database) CountJobs(submission string) (map[JobStatus]int, error) {
+	rows, err := db.db.Query(`SELECT status, COUNT(*) FROM jobs WHERE submission = ? GROUP BY status;`, submission)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[JobStatus]int)
+	for rows.Next() {
+		var status JobStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// FailureGroup gathers failed jobs with the same error.
+type FailureGroup struct {
+	Error       string
+	Count       int
+	MaxAttempts int
+	Example     string // Instrument and time range of one of the jobs
+}
+
+// FailureReport returns failed jobs grouped by error, most frequent first.
+func (db *Database) FailureReport() ([]*FailureGroup, error) {
+	rows, err := db.db.Query(`
+    SELECT
+        error,
+        COUNT(*),
+        MAX(attempts),
+        MIN(instrument || ' ' || time_range)
+    FROM jobs
+    WHERE status = ?
+    GROUP BY error
+    ORDER BY COUNT(*) DESC;`,
+		JobStatusFailed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*FailureGroup{}
+	for rows.Next() {
+		var g FailureGroup
+		if err := rows.Scan(&g.Error, &g.Count, &g.MaxAttempts, &g.Example); err != nil {
+			return nil, err
+		}
+		groups = append(groups, &g)
+	}
+
+	return groups, rows.Err()
+}
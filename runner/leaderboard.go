@@ -0,0 +1,204 @@
+package runner
+
+import (
+	"crypto/md5"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// StrategyStats aggregates the runs of a strategy across months and instruments, under the same settings.
+type StrategyStats struct {
+	StrategyID  string   `json:"strategyId"` // Short hash of the strategy
+	Strategy    string   `json:"strategy"`   // Serialized strategy description
+	Kind        string   `json:"kind"`       // e.g., modular, expression
+	SettingsID  string   `json:"settingsId"` // Short hash of the settings
+	Settings    string   `json:"settings"`   // Serialized run settings
+	Instruments []string `json:"instruments"`
+
+	Months         int     `json:"months"` // Number of runs (month x instrument)
+	TotalTrades    int     `json:"totalTrades"`
+	NetPnL         float64 `json:"netPnL"`
+	WinRate        float64 `json:"winRate"`        // Trade-weighted, in percent
+	ExpectedValueR float64 `json:"expectedValueR"` // Trade-weighted
+	MaxDrawdownPct float64 `json:"maxDrawdownPct"` // Worst monthly drawdown
+
+	// Consistency
+	ProfitableMonthsPct float64 `json:"profitableMonthsPct"`
+	WorstMonth          string  `json:"worstMonth"` // instrument and time range
+	WorstMonthPnL       float64 `json:"worstMonthPnL"`
+	MeanMonthlyPnL      float64 `json:"meanMonthlyPnL"`
+	StdevMonthlyPnL     float64 `json:"stdevMonthlyPnL"`
+	SignificantMonths   int     `json:"significantMonths"`
+}
+
+// Objective ranks strategies, higher is better.
+type Objective string
+
+const (
+	ObjectiveNetPnL           Objective = "netPnL"
+	ObjectiveExpectancy       Objective = "expectancy"
+	ObjectiveProfitableMonths Objective = "profitableMonths"
+	ObjectiveWorstMonth       Objective = "worstMonth"
+	ObjectiveSharpe           Objective = "sharpe"      // Mean / stdev of monthly PnL
+	ObjectiveMaxDrawdown      Objective = "maxDrawdown" // Negated worst monthly drawdown, so that a lower drawdown is better
+	ObjectiveTrades           Objective = "trades"
+)
+
+var Objectives = []Objective{
+	ObjectiveNetPnL,
+	ObjectiveExpectancy,
+	ObjectiveProfitableMonths,
+	ObjectiveWorstMonth,
+	ObjectiveSharpe,
+	ObjectiveMaxDrawdown,
+	ObjectiveTrades,
+}
+
+func ParseObjective(value string) (Objective, error) {
+	for _, objective := range Objectives {
+		if string(objective) == value {
+			return objective, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown objective: %s", value)
+}
+
+func (o Objective) Value(stats *StrategyStats) float64 {
+	switch o {
+	case ObjectiveNetPnL:
+		return stats.NetPnL
+	case ObjectiveExpectancy:
+		return stats.ExpectedValueR
+	case ObjectiveProfitableMonths:
+		return stats.ProfitableMonthsPct
+	case ObjectiveWorstMonth:
+		return stats.WorstMonthPnL
+	case ObjectiveSharpe:
+		if stats.StdevMonthlyPnL == 0 {
+			return 0
+		}
+		return stats.MeanMonthlyPnL / stats.StdevMonthlyPnL
+	case ObjectiveMaxDrawdown:
+		return -stats.MaxDrawdownPct
+	case ObjectiveTrades:
+		return float64(stats.TotalTrades)
+	default:
+		panic(fmt.Sprintf("unknown objective: %s", o))
+	}
+}
+
+func StrategyID(strategy string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(strategy)))[:8]
+}
+
+// SettingsID returns the short hash of serialized run settings.
+func SettingsID(settings string) string {
+	return StrategyID(settings)
+}
+
+// strategySettings identifies the runs aggregated together.
+type strategySettings struct {
+	strategy, settings string
+}
+
+// AggregateByStrategy groups runs by strategy and settings, and computes their stats.
+// runs must have a single run range per instrument and month (see RunFilter.RunRanges and SingleMonths),
+// otherwise the months of overlapping runs are aggregated together.
+func AggregateByStrategy(runs []*run) []*StrategyStats {
+	byStrategy := make(map[strategySettings][]*run)
+	order := []strategySettings{}
+
+	for _, r := range runs {
+		key := strategySettings{r.Strategy, r.Settings}
+		if _, exists := byStrategy[key]; !exists {
+			order = append(order, key)
+		}
+		byStrategy[key] = append(byStrategy[key], r)
+	}
+
+	result := make([]*StrategyStats, 0, len(order))
+	for _, key := range order {
+		result = append(result, aggregateRuns(key.strategy, byStrategy[key]))
+	}
+
+	return result
+}
+
+// aggregateRuns computes the stats of runs of a strategy, with the settings of the first run.
+func aggregateRuns(strategy string, runs []*run) *StrategyStats {
+	stats := &StrategyStats{
+		StrategyID:    StrategyID(strategy),
+		Strategy:      strategy,
+		Kind:          runs[0].StrategyKind,
+		SettingsID:    SettingsID(runs[0].Settings),
+		Settings:      runs[0].Settings,
+		Instruments:   []string{},
+		Months:        len(runs),
+		WorstMonthPnL: math.Inf(1),
+	}
+
+	var winningTrades, totalR float64
+	var profitableMonths int
+
+	for _, r := range runs {
+		if !slices.Contains(stats.Instruments, r.Instrument) {
+			stats.Instruments = append(stats.Instruments, r.Instrument)
+		}
+
+		stats.TotalTrades += r.TotalTrades
+		stats.NetPnL += r.NetPnL
+		winningTrades += r.WinRate * float64(r.TotalTrades) / 100
+		totalR += r.ExpectedValueR * float64(r.TotalTrades)
+		stats.MaxDrawdownPct = math.Max(stats.MaxDrawdownPct, r.MaxDrawdownPct)
+
+		if r.NetPnL > 0 {
+			profitableMonths++
+		}
+		if r.NetPnL < stats.WorstMonthPnL {
+			stats.WorstMonthPnL = r.NetPnL
+			stats.WorstMonth = fmt.Sprintf("%s %s", r.Instrument, r.TimeRange)
+		}
+		if r.Significant {
+			stats.SignificantMonths++
+		}
+	}
+
+	slices.Sort(stats.Instruments)
+
+	if stats.TotalTrades > 0 {
+		stats.WinRate = winningTrades / float64(stats.TotalTrades) * 100
+		stats.ExpectedValueR = totalR / float64(stats.TotalTrades)
+	}
+
+	if stats.Months > 0 {
+		stats.ProfitableMonthsPct = float64(profitableMonths) / float64(stats.Months) * 100
+		stats.MeanMonthlyPnL = stats.NetPnL / float64(stats.Months)
+	}
+
+	if stats.Months > 1 {
+		var sq float64
+		for _, r := range runs {
+			sq += (r.NetPnL - stats.MeanMonthlyPnL) * (r.NetPnL - stats.MeanMonthlyPnL)
+		}
+		stats.StdevMonthlyPnL = math.Sqrt(sq / float64(stats.Months-1))
+	}
+
+	return stats
+}
+
+// RankStrategies sorts stats by objective, best first.
+func RankStrategies(stats []*StrategyStats, objective Objective) {
+	slices.SortStableFunc(stats, func(a, b *StrategyStats) int {
+		va, vb := objective.Value(a), objective.Value(b)
+		switch {
+		case va > vb:
+			return -1
+		case va < vb:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
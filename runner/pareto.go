@@ -0,0 +1,187 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Criterion is an objective of a multi-objective optimisation, maximised like a single objective.
+type Criterion struct {
+	Objective Objective
+	Weight    float64 // In the weighted scalarisation
+}
+
+// Requirement is a minimum value of an objective (e.g., 30 trades at least).
+// Strategies that do not meet it are not candidates of the Pareto front.
+type Requirement struct {
+	Objective Objective
+	Min       float64
+}
+
+// MultiObjective ranks strategies by several objectives at once.
+type MultiObjective struct {
+	Criteria     []*Criterion
+	Requirements []*Requirement
+}
+
+// ParseMultiObjective parses comma-separated criteria and requirements.
+// A criterion is an objective with an optional weight (e.g., expectancy:2), 1 by default.
+// A requirement is an objective with a minimum (e.g., trades>=30).
+//
+// For example: expectancy:2,maxDrawdown,trades>=30
+func ParseMultiObjective(value string) (*MultiObjective, error) {
+	mo := &MultiObjective{}
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+
+		if name, min, ok := strings.Cut(part, ">="); ok {
+			objective, err := ParseObjective(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			threshold, err := strconv.ParseFloat(strings.TrimSpace(min), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid minimum of %s: %w", objective, err)
+			}
+			mo.Requirements = append(mo.Requirements, &Requirement{Objective: objective, Min: threshold})
+			continue
+		}
+
+		name, weight, hasWeight := strings.Cut(part, ":")
+		objective, err := ParseObjective(name)
+		if err != nil {
+			return nil, err
+		}
+
+		criterion := &Criterion{Objective: objective, Weight: 1}
+		if hasWeight {
+			criterion.Weight, err = strconv.ParseFloat(weight, 64)
+			if err != nil || criterion.Weight < 0 {
+				return nil, fmt.Errorf("invalid weight of %s: %s", objective, weight)
+			}
+		}
+
+		if slices.ContainsFunc(mo.Criteria, func(c *Criterion) bool { return c.Objective == objective }) {
+			return nil, fmt.Errorf("duplicate objective %s", objective)
+		}
+		mo.Criteria = append(mo.Criteria, criterion)
+	}
+
+	if len(mo.Criteria) == 0 {
+		return nil, fmt.Errorf("no objective in %q", value)
+	}
+
+	return mo, nil
+}
+
+func (mo *MultiObjective) String() string {
+	parts := make([]string, 0, len(mo.Criteria)+len(mo.Requirements))
+	for _, c := range mo.Criteria {
+		parts = append(parts, fmt.Sprintf("%s:%g", c.Objective, c.Weight))
+	}
+	for _, r := range mo.Requirements {
+		parts = append(parts, fmt.Sprintf("%s>=%g", r.Objective, r.Min))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Meets returns true if the stats meet all requirements.
+func (mo *MultiObjective) Meets(stats *StrategyStats) bool {
+	for _, r := range mo.Requirements {
+		if r.Objective.Value(stats) < r.Min {
+			return false
+		}
+	}
+	return true
+}
+
+// Values returns the value of each criterion.
+func (mo *MultiObjective) Values(stats *StrategyStats) []float64 {
+	values := make([]float64, len(mo.Criteria))
+	for i, c := range mo.Criteria {
+		values[i] = c.Objective.Value(stats)
+	}
+	return values
+}
+
+// dominates returns true if a is at least as good as b on all criteria, and better on one.
+func dominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		if a[i] < b[i] {
+			return false
+		}
+		if a[i] > b[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// ParetoPoint is a strategy of the Pareto front.
+type ParetoPoint struct {
+	Stats  *StrategyStats
+	Values []float64 // Of each criterion
+	Score  float64   // Weighted scalarisation, between 0 and the sum of the weights
+}
+
+// ParetoFront returns the strategies that meet the requirements and that no other one dominates,
+// best weighted score first.
+//
+// The score sums the weighted criteria, each normalised between the worst (0) and the best (1) value of the candidates,
+// so that objectives of different scales (e.g., R and percents) are comparable.
+func (mo *MultiObjective) ParetoFront(stats []*StrategyStats) []*ParetoPoint {
+	var candidates []*ParetoPoint
+	for _, s := range stats {
+		if mo.Meets(s) {
+			candidates = append(candidates, &ParetoPoint{Stats: s, Values: mo.Values(s)})
+		}
+	}
+
+	mo.score(candidates)
+
+	var front []*ParetoPoint
+	for _, candidate := range candidates {
+		dominated := slices.ContainsFunc(candidates, func(other *ParetoPoint) bool {
+			return dominates(other.Values, candidate.Values)
+		})
+		if !dominated {
+			front = append(front, candidate)
+		}
+	}
+
+	slices.SortStableFunc(front, func(a, b *ParetoPoint) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return front
+}
+
+func (mo *MultiObjective) score(points []*ParetoPoint) {
+	for i, c := range mo.Criteria {
+		worst, best := math.Inf(1), math.Inf(-1)
+		for _, p := range points {
+			worst = math.Min(worst, p.Values[i])
+			best = math.Max(best, p.Values[i])
+		}
+
+		for _, p := range points {
+			// All candidates are equal on this criterion, which does not separate them
+			if best == worst {
+				continue
+			}
+			p.Score += c.Weight * (p.Values[i] - worst) / (best - worst)
+		}
+	}
+}
@@ -1,50 +1,163 @@
 package runner
 
 import (
+	"context"
 	"runtime"
 	"sync"
+	"time"
 )
 
-type Task func()
+// ProgressReportInterval is the interval at which the pool logs its progress.
+const ProgressReportInterval = 30 * time.Second
 
+type Task func() error
+
+// TaskPool runs tasks on one goroutine per CPU core.
+// Tasks are queued by group (e.g., the dataset they use): groups run in order of submission,
+// so that the tasks of a group run together.
 type TaskPool struct {
-	wg     sync.WaitGroup
-	tasks  chan Task
-	closed bool
+	ctx        context.Context
+	wg         sync.WaitGroup
+	lock       sync.Mutex
+	cond       *sync.Cond
+	groups     []string          // Groups with queued tasks, in order of submission
+	queues     map[string][]Task // Queued tasks by group
+	closed     bool
+	progress   *Progress
+	stopReport chan struct{}
 }
 
-func NewTaskPool() *TaskPool {
+// NewTaskPool creates a pool that runs tasks until ctx is canceled.
+// On cancellation, running tasks finish normally and queued tasks are skipped.
+func NewTaskPool(ctx context.Context) *TaskPool {
 	workers := runtime.NumCPU() // Use number of CPU cores as default
 
 	p := &TaskPool{
-		tasks: make(chan Task, 10000000),
+		ctx:        ctx,
+		queues:     make(map[string][]Task),
+		progress:   newProgress(ctx),
+		stopReport: make(chan struct{}),
 	}
+	p.cond = sync.NewCond(&p.lock)
 
 	p.wg.Add(workers)
 
 	for i := 0; i < workers; i++ {
 		go func(id int) {
 			defer p.wg.Done()
-			for task := range p.tasks {
-				task()
+			for {
+				task, ok := p.next()
+				if !ok {
+					return
+				}
+				p.execute(task)
 			}
 		}(i)
 	}
 
+	go p.report()
+
 	return p
 }
 
-func (p *TaskPool) Submit(task Task) {
+// next returns the next task of the first group, waiting for one if needed.
+// It returns false once the pool is closed and all tasks are taken.
+func (p *TaskPool) next() (Task, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for len(p.groups) == 0 && !p.closed {
+		p.cond.Wait()
+	}
+	if len(p.groups) == 0 {
+		return nil, false
+	}
+
+	group := p.groups[0]
+	queue := p.queues[group]
+	task := queue[0]
+
+	if len(queue) == 1 {
+		delete(p.queues, group)
+		p.groups = p.groups[1:]
+	} else {
+		p.queues[group] = queue[1:]
+	}
+
+	return task, true
+}
+
+func (p *TaskPool) execute(task Task) {
+	p.progress.queued.Add(-1)
+
+	if p.ctx.Err() != nil {
+		p.progress.skipped.Add(1)
+		return
+	}
+
+	p.progress.running.Add(1)
+	err := task()
+	p.progress.running.Add(-1)
+
+	if err != nil {
+		p.progress.failed.Add(1)
+	} else {
+		p.progress.done.Add(1)
+	}
+}
+
+func (p *TaskPool) report() {
+	ticker := time.NewTicker(ProgressReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			log.Info("⏳ Progress: %s", p.progress.Snapshot().String())
+		case <-p.stopReport:
+			return
+		}
+	}
+}
+
+// Submit enqueues a task in a group. It returns the context error if the pool is canceled.
+func (p *TaskPool) Submit(group string, task Task) error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
 	if p.closed {
 		panic("submit on closed pool")
 	}
-	p.tasks <- task
+
+	if _, exists := p.queues[group]; !exists {
+		p.groups = append(p.groups, group)
+	}
+	p.queues[group] = append(p.queues[group], task)
+	p.progress.queued.Add(1)
+
+	p.cond.Signal()
+	return nil
+}
+
+func (p *TaskPool) Progress() *Progress {
+	return p.progress
 }
 
 func (p *TaskPool) Close() {
-	if !p.closed {
-		p.closed = true
-		close(p.tasks)
-		p.wg.Wait()
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return
 	}
+	p.closed = true
+	p.cond.Broadcast()
+	p.lock.Unlock()
+
+	p.wg.Wait()
+	close(p.stopReport)
+	log.Info("🏁 Finished: %s", p.progress.Snapshot().String())
 }
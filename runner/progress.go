@@ -0,0 +1,91 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Progress tracks the tasks of a pool.
+type Progress struct {
+	ctx     context.Context // Of the pool, queued tasks are skipped once it is canceled
+	start   time.Time
+	queued  atomic.Int64
+	running atomic.Int64
+	done    atomic.Int64
+	failed  atomic.Int64
+	skipped atomic.Int64
+	cached  atomic.Int64
+}
+
+func newProgress(ctx context.Context) *Progress {
+	return &Progress{ctx: ctx, start: time.Now()}
+}
+
+type ProgressSnapshot struct {
+	Queued         int64         `json:"queued"`
+	Running        int64         `json:"running"`
+	Done           int64         `json:"done"`
+	Failed         int64         `json:"failed"`
+	Skipped        int64         `json:"skipped"` // Not started because of cancellation
+	Cached         int64         `json:"cached"`  // Submitted with results already saved, never queued
+	Elapsed        time.Duration `json:"-"`
+	ElapsedSeconds float64       `json:"elapsedSeconds"`
+	Throughput     float64       `json:"throughput"` // Finished tasks per second
+	ETA            time.Duration `json:"-"`          // 0 if unknown
+	ETASeconds     float64       `json:"etaSeconds"` // 0 if unknown
+}
+
+// addCached records a submitted run whose results are already saved.
+func (p *Progress) addCached() {
+	p.cached.Add(1)
+}
+
+func (p *Progress) Snapshot() ProgressSnapshot {
+	s := ProgressSnapshot{
+		Queued:  p.queued.Load(),
+		Running: p.running.Load(),
+		Done:    p.done.Load(),
+		Failed:  p.failed.Load(),
+		Skipped: p.skipped.Load(),
+		Cached:  p.cached.Load(),
+		Elapsed: time.Since(p.start),
+	}
+
+	// Only run tasks count, cached ones are never queued and queued ones are skipped once canceled
+	finished := s.Done + s.Failed
+	if finished > 0 && s.Elapsed > 0 {
+		s.Throughput = float64(finished) / s.Elapsed.Seconds()
+		remaining := s.Running
+		if p.ctx.Err() == nil {
+			remaining += s.Queued
+		}
+		s.ETA = time.Duration(float64(remaining) / s.Throughput * float64(time.Second))
+	}
+
+	s.ElapsedSeconds = s.Elapsed.Seconds()
+	s.ETASeconds = s.ETA.Seconds()
+
+	return s
+}
+
+func (s ProgressSnapshot) String() string {
+	eta := "unknown"
+	if s.ETA > 0 {
+		eta = s.ETA.Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("queued=%d running=%d done=%d failed=%d skipped=%d cached=%d throughput=%.2f/s elapsed=%s eta=%s",
+		s.Queued, s.Running, s.Done, s.Failed, s.Skipped, s.Cached, s.Throughput, s.Elapsed.Round(time.Second), eta)
+}
+
+// ServeHTTP implements http.Handler, serving the progress as JSON.
+func (p *Progress) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p.Snapshot()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,143 +1,517 @@
 package runner
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"sync"
+	"time"
 	"trading-bot/brokers/backtesting"
 	"trading-bot/common"
 	"trading-bot/traders"
-	"trading-bot/traders/modular"
 )
 
 var log = common.NewLogger("runner")
 
 type Runner struct {
-	db       *Database
-	datasets *datasets
-	pool     *TaskPool
+	db           *Database
+	datasets     *datasets
+	fingerprints *fingerprints
+	pool         *TaskPool    // nil in distributed mode
+	coordinator  *Coordinator // nil in local mode
+
+	lock     sync.Mutex
+	inFlight map[string]bool // Keys of the jobs queued or running in the local pool
+}
+
+// NewRunner creates a runner that runs submitted backtests until ctx is canceled.
+// Runs that are not started on cancellation stay pending in the jobs table, so they can be resumed by the next invocation.
+func NewRunner(ctx context.Context) (*Runner, error) {
+	db, err := OpenDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Runner{
+		db:           db,
+		datasets:     newDatasets(DefaultDatasetBudget),
+		fingerprints: newFingerprints(),
+		pool:         NewTaskPool(ctx),
+		inFlight:     make(map[string]bool),
+	}, nil
 }
 
-func NewRunner() (*Runner, error) {
+// NewDistributedRunner creates a runner that only records submitted backtests as jobs,
+// and serves them to workers (see Worker) on a local HTTP address (e.g., localhost:8082).
+// Workers rebuild strategies from their identity, so strategies that cannot be rebuilt (e.g., expression strategies) are rejected.
+// Close waits until the submitted jobs are finished or ctx is canceled, other jobs of the database are not served.
+func NewDistributedRunner(ctx context.Context, addr string) (*Runner, error) {
 	db, err := OpenDatabase()
 	if err != nil {
 		return nil, err
 	}
 
+	coordinator := NewCoordinator(ctx, db)
+	coordinator.Serve(addr)
+
 	return &Runner{
-		db:       db,
-		datasets: newDatasets(),
-		pool:     NewTaskPool(),
+		db:           db,
+		fingerprints: newFingerprints(),
+		coordinator:  coordinator,
 	}, nil
 }
 
+// SetDatasetBudget changes the memory budget of the dataset cache, in bytes.
+func (r *Runner) SetDatasetBudget(budget int64) {
+	if r.datasets != nil {
+		r.datasets.SetBudget(budget)
+	}
+}
+
+// Progress returns the progress of the local pool, nil in distributed mode.
+func (r *Runner) Progress() *Progress {
+	if r.pool == nil {
+		return nil
+	}
+	return r.pool.Progress()
+}
+
+// ServeProgress serves the progress as JSON on a local HTTP endpoint (e.g., localhost:8081).
+func (r *Runner) ServeProgress(addr string) {
+	if r.pool == nil {
+		log.Warning("Progress is served by the coordinator in distributed mode")
+		return
+	}
+
+	go func() {
+		log.Info("Serving progress on http://%s/", addr)
+		if err := http.ListenAndServe(addr, r.Progress()); err != nil {
+			log.Error("Failed to serve progress: %v", err)
+		}
+	}()
+}
+
 func (r *Runner) Close() {
-	r.pool.Close()
+	if r.pool != nil {
+		r.pool.Close()
+	}
+	if r.coordinator != nil {
+		r.coordinator.Close()
+	}
 	r.db.Close()
 }
 
-func (r *Runner) SubmitRun(instrument string, month common.Month, strategy modular.Builder) error {
+// SubmitRun submits a backtest of a single month.
+func (r *Runner) SubmitRun(instrument string, month common.Month, strategy traders.Strategy, settings *Settings) error {
+	return r.SubmitRange(instrument, month, month, strategy, settings)
+}
+
+// SubmitRange submits a continuous backtest from the first day of from to the last day of to.
+// Positions are carried over between months, but results are still saved per month.
+// With a warm-up in the settings, prior data fills the history of the trader, which does not trade on it.
+func (r *Runner) SubmitRange(instrument string, from, to common.Month, strategy traders.Strategy, settings *Settings) error {
+	_, err := r.submitRange(instrument, from, to, strategy, settings)
+	return err
+}
+
+// submitRange returns the key of the submitted job, empty if the results are already saved.
+func (r *Runner) submitRange(instrument string, from, to common.Month, strategy traders.Strategy, settings *Settings) (string, error) {
+	timeRange := common.FormatMonthRange(from, to)
+
+	// Workers only receive the identity of the strategy
+	if r.coordinator != nil {
+		if _, err := traders.StrategyFromIdentity(strategy.Kind(), strategy.Identity()); err != nil {
+			return "", fmt.Errorf("strategy cannot be run by workers: %w", err)
+		}
+	}
+
+	if err := checkHistory(from, to, strategy, settings); err != nil {
+		return "", err
+	}
+
+	j, err := r.newJob(instrument, from, to, strategy, settings)
+	if err != nil {
+		return "", err
+	}
+
 	// Try to see if output is already cached
-	strategyStr := modular.ToJSON(strategy)
+	cached, err := r.isCached(j, from)
+	if err != nil {
+		return "", err
+	}
 
-	run, err := r.db.FindRun(instrument, month.String(), strategyStr)
+	if cached {
+		log.Info("Run already exists for %s %s: %s", instrument, timeRange, strategy.Description())
+		if r.pool != nil {
+			r.pool.Progress().addCached()
+		}
+		return "", nil
+	}
+
+	submitted, err := r.isSubmitted(j.Key)
+	if err != nil {
+		return "", err
+	}
+	if submitted {
+		return j.Key, nil
+	}
+
+	if err := r.db.SaveJob(j); err != nil {
+		return "", fmt.Errorf("failed to save job: %w", err)
+	}
+
+	return j.Key, r.enqueue(j, from, to, strategy, settings)
+}
+
+// checkHistory returns an error if the histories of the strategy are not filled before the end of the run,
+// as the strategy would never trade.
+func checkHistory(from, to common.Month, strategy traders.Strategy, settings *Settings) error {
+	history, err := strategy.HistoryDuration()
 	if err != nil {
 		return err
 	}
 
-	if run != nil {
-		log.Info("Run already exists for %s %s: %s", instrument, month.String(), strategy.Format().Compact())
-		return nil
+	// Markets are closed on weekends, five days of market data take about a week
+	needed := history * 7 / 5
+	available := settings.WarmUp + to.AddMonths(1).FirstDay().Sub(from.FirstDay())
+
+	if needed > available {
+		return fmt.Errorf("the histories of the strategy need about %s of data, more than the run and its warm-up (%s)",
+			needed.Round(time.Hour), available.Round(time.Hour))
 	}
+	return nil
+}
 
-	// enqueue run
-	r.pool.Submit(func() {
-		if err := r.run(instrument, month, strategy); err != nil {
-			log.Error("Failed to run strategy for %s %s: %v", instrument, month.String(), err)
+// isSubmitted returns true if the job is already submitted and not finished, so that it is not run twice.
+func (r *Runner) isSubmitted(key string) (bool, error) {
+	if r.coordinator != nil {
+		// Pending and running jobs are leased by the workers, running ones again if their lease expires
+		j, err := r.db.FindJob(key)
+		if err != nil {
+			return false, fmt.Errorf("failed to find job: %w", err)
+		}
+		if j == nil || (j.Status != JobStatusPending && j.Status != JobStatusRunning) {
+			return false, nil
 		}
-	})
 
-	return nil
+		// A job left unfinished by another process is served, and waited for, by this coordinator
+		if j.Submission != r.coordinator.submission {
+			log.Info("Adopting %s job %s of %s %s", j.Status, j.Key, j.Instrument, j.TimeRange)
+			if err := r.db.AdoptJob(key, r.coordinator.submission); err != nil {
+				return false, fmt.Errorf("failed to adopt job: %w", err)
+			}
+		}
+		return true, nil
+	}
+
+	// Jobs left pending by a previous process are submitted again
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.inFlight[key], nil
+}
+
+func (r *Runner) setInFlight(key string, inFlight bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if inFlight {
+		r.inFlight[key] = true
+	} else {
+		delete(r.inFlight, key)
+	}
 }
 
-func (r *Runner) run(instrument string, month common.Month, strategy modular.Builder) error {
-	log.Info("Running strategy for %s %s: %s", instrument, month.String(), strategy.Format().Compact())
+func (r *Runner) newJob(instrument string, from, to common.Month, strategy traders.Strategy, settings *Settings) (*job, error) {
+	warmUpFrom, _ := dataRange(from, to, settings)
 
-	dataset, err := r.datasets.Get(instrument, month)
+	fingerprint, err := r.fingerprints.Get(instrument, warmUpFrom, to, settings)
 	if err != nil {
-		return fmt.Errorf("failed to get dataset for %s %s: %w", instrument, month.String(), err)
+		return nil, err
 	}
 
-	brokerConfig := &backtesting.Config{
-		// For backtesting, we assume a lot size of 1 for simplicity.
-		// In a real broker, this would be the number of units per lot.
-		// Not that using IG broker, EUR/USD Mini has also a size of 1.
-		LotSize: 1,
+	timeRange := common.FormatMonthRange(from, to)
 
-		// Leverage is the ratio of the amount of capital that a trader must put up to open a position.
-		// For example, if the leverage is 30, it means that for every 1 unit of capital,
-		// the trader can control 30 units of the asset.
-		// This is a common leverage ratio in forex trading.
-		Leverage: 30.0,
+	submission := ""
+	if r.coordinator != nil {
+		submission = r.coordinator.submission
+	}
 
-		InitialCapital: 100000,
+	return &job{
+		Key:           r.db.ComputeKey(instrument, timeRange, strategy.Identity(), settings.Identity(), fingerprint),
+		Instrument:    instrument,
+		TimeRange:     timeRange,
+		Strategy:      strategy.Identity(),
+		StrategyKind:  string(strategy.Kind()),
+		Settings:      settings.Identity(),
+		EngineVersion: backtesting.EngineVersion,
+		Fingerprint:   fingerprint,
+		Submission:    submission,
+	}, nil
+}
+
+// isCached returns true if the results of a job are saved. All months of a job are saved at once.
+func (r *Runner) isCached(j *job, from common.Month) (bool, error) {
+	run, err := r.db.FindRun(monthRunKey(j, from))
+	if err != nil {
+		return false, err
 	}
 
-	broker, err := backtesting.NewBroker(brokerConfig, dataset)
+	return run != nil, nil
+}
+
+// ResumeJobs submits again the jobs of the database that did not complete (pending, failed, or interrupted while running).
+// It returns the number of submitted jobs. Jobs that cannot be rebuilt (e.g., expression strategies) are skipped.
+// Jobs submitted by a previous engine version, or on other data, are replaced by jobs with the current fingerprint.
+// Jobs whose results are already saved are finished without running them again.
+func (r *Runner) ResumeJobs() (int, error) {
+	jobs, err := r.db.FindUnfinishedJobs()
 	if err != nil {
-		return fmt.Errorf("failed to create broker: %w", err)
+		return 0, fmt.Errorf("failed to find jobs: %w", err)
 	}
 
-	if err := traders.SetupModularTrader(broker, strategy); err != nil {
-		return fmt.Errorf("failed to setup trader: %w", err)
+	submitted := 0
+	for _, previous := range jobs {
+		from, to, err := common.ParseMonthRange(previous.TimeRange)
+		if err != nil {
+			log.Warning("Skipping job %s: %v", previous.Key, err)
+			continue
+		}
+
+		strategy, err := traders.StrategyFromIdentity(traders.StrategyKind(previous.StrategyKind), previous.Strategy)
+		if err != nil {
+			log.Warning("Skipping job %s: %v", previous.Key, err)
+			continue
+		}
+
+		var settings Settings
+		if err := json.Unmarshal([]byte(previous.Settings), &settings); err != nil {
+			log.Warning("Skipping job %s: invalid settings: %v", previous.Key, err)
+			continue
+		}
+
+		j, err := r.newJob(previous.Instrument, from, to, strategy, &settings)
+		if err != nil {
+			return submitted, err
+		}
+
+		if j.Key != previous.Key {
+			log.Info("Replacing job %s of engine version %d", previous.Key, previous.EngineVersion)
+			if err := r.db.DeleteJob(previous.Key); err != nil {
+				return submitted, fmt.Errorf("failed to delete job: %w", err)
+			}
+		}
+
+		// Runs of a job interrupted after saving them, or already run with the current fingerprint
+		cached, err := r.isCached(j, from)
+		if err != nil {
+			return submitted, err
+		}
+		if cached {
+			if j.Key == previous.Key {
+				if err := r.db.FinishJob(j.Key, nil); err != nil {
+					return submitted, fmt.Errorf("failed to finish job: %w", err)
+				}
+			}
+			continue
+		}
+
+		if err := r.db.SaveJob(j); err != nil {
+			return submitted, fmt.Errorf("failed to save job: %w", err)
+		}
+		if err := r.enqueue(j, from, to, strategy, &settings); err != nil {
+			return submitted, err
+		}
+
+		submitted++
+	}
+
+	return submitted, nil
+}
+
+// FailureReport returns failed jobs grouped by error.
+func (r *Runner) FailureReport() ([]*FailureGroup, error) {
+	return r.db.FailureReport()
+}
+
+func (r *Runner) enqueue(j *job, from, to common.Month, strategy traders.Strategy, settings *Settings) error {
+	if r.coordinator != nil {
+		// Pending jobs are leased by the workers
+		return r.coordinator.ctx.Err()
+	}
+
+	// Group tasks by dataset, so that each dataset is loaded as few times as possible
+	dataFrom, dataTo := dataRange(from, to, settings)
+	group := datasetKey(settings.DataSource, j.Instrument, dataFrom, dataTo)
+
+	r.setInFlight(j.Key, true)
+
+	err := r.pool.Submit(group, func() error {
+		defer r.setInFlight(j.Key, false)
+
+		if err := r.db.StartJob(j.Key); err != nil {
+			log.Error("Failed to start job %s: %v", j.Key, err)
+		}
+
+		err := r.run(j, from, to, strategy, settings)
+		if err != nil {
+			log.Error("Failed to run strategy for %s %s: %v", j.Instrument, j.TimeRange, err)
+		}
+
+		if jobErr := r.db.FinishJob(j.Key, err); jobErr != nil {
+			log.Error("Failed to finish job %s: %v", j.Key, jobErr)
+		}
+
+		return err
+	})
+	if err != nil {
+		r.setInFlight(j.Key, false)
+	}
+
+	return err
+}
+
+func (r *Runner) run(j *job, from, to common.Month, strategy traders.Strategy, settings *Settings) error {
+	results, err := backtest(r.datasets, j.Instrument, from, to, strategy, settings)
+	if err != nil {
+		return err
+	}
+
+	if err := r.db.SaveRuns(newRuns(j, settings, results)); err != nil {
+		return fmt.Errorf("failed to save result: %w", err)
+	}
+
+	log.Info("Run completed for %s %s: %s", j.Instrument, j.TimeRange, strategy.Description())
+	return nil
+}
+
+// monthResult holds the results of a month of a backtest.
+type monthResult struct {
+	TimeRange string // Month (e.g., 2023-01)
+	Metrics   *backtesting.Metrics
+	Trades    []*backtesting.Trade // Trades opened during the month
+}
+
+// dataRange returns the months of data needed by a backtest, including its warm-up.
+func dataRange(from, to common.Month, settings *Settings) (common.Month, common.Month) {
+	return common.FromDate(from.FirstDay().Add(-settings.WarmUp)), to
+}
+
+// backtest runs a strategy from the first day of from to the last day of to, and returns its results per month.
+func backtest(datasets *datasets, instrument string, from, to common.Month, strategy traders.Strategy, settings *Settings) ([]*monthResult, error) {
+	timeRange := common.FormatMonthRange(from, to)
+	log.Info("Running strategy for %s %s: %s", instrument, timeRange, strategy.Description())
+
+	dataFrom, dataTo := dataRange(from, to, settings)
+	dataset, release, err := datasets.Get(settings.DataSource, instrument, dataFrom, dataTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dataset for %s %s: %w", instrument, timeRange, err)
+	}
+	defer release()
+
+	tradingStart := from.FirstDay()
+	dataset = dataset.From(tradingStart.Add(-settings.WarmUp))
+
+	brokerConfig := settings.Broker
+
+	broker, err := backtesting.NewBroker(&brokerConfig, dataset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create broker: %w", err)
+	}
+	if err := backtesting.SetTradingStart(broker, tradingStart); err != nil {
+		return nil, fmt.Errorf("failed to set trading start: %w", err)
+	}
+
+	if err := strategy.Setup(broker); err != nil {
+		return nil, fmt.Errorf("failed to setup trader: %w", err)
 	}
 	if err := broker.Run(); err != nil {
-		return fmt.Errorf("failed to run broker: %w", err)
+		return nil, fmt.Errorf("failed to run broker: %w", err)
 	}
 
 	metrics, err := backtesting.ComputeMetrics(broker)
 	if err != nil {
-		return fmt.Errorf("failed to compute metrics: %w", err)
+		return nil, fmt.Errorf("failed to compute metrics: %w", err)
 	}
 
-	var metrics0 *backtesting.Metrics
+	trades, err := backtesting.GetAllTrades(broker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trades: %w", err)
+	}
 
-	switch len(metrics) {
-	case 0:
-		// No metrics means no position has been taken
-		metrics0 = &backtesting.Metrics{}
+	months := common.Months(from, to)
+	results := make([]*monthResult, len(months))
+	resultsByMonth := make(map[common.Month]*monthResult)
 
-	case 1:
-		var ok bool
-		metrics0, ok = metrics[month]
+	for i, month := range months {
+		monthMetrics, ok := metrics[month]
 		if !ok {
-			return fmt.Errorf("no metrics found for month %s", month.String())
+			// No metrics means no position has been taken
+			monthMetrics = &backtesting.Metrics{}
 		}
 
-	default:
-		return fmt.Errorf("expected exactly one metric, got %d", len(metrics))
+		results[i] = &monthResult{
+			TimeRange: month.String(),
+			Metrics:   monthMetrics,
+			Trades:    make([]*backtesting.Trade, 0),
+		}
+		resultsByMonth[month] = results[i]
 	}
 
-	if err := r.saveResult(instrument, month, strategy, metrics0); err != nil {
-		return fmt.Errorf("failed to save result: %w", err)
+	if len(metrics) > len(months) {
+		return nil, fmt.Errorf("expected metrics of %d months at most, got %d", len(months), len(metrics))
 	}
 
-	log.Info("Run completed for %s %s: %s", instrument, month.String(), strategy.Format().Compact())
-	return nil
-}
+	for _, trade := range trades {
+		result, ok := resultsByMonth[common.FromDate(trade.OpenTime)]
+		if !ok {
+			return nil, fmt.Errorf("trade opened outside of %s at %s", timeRange, trade.OpenTime.Format("2006-01-02 15:04:05"))
+		}
+		result.Trades = append(result.Trades, trade)
+	}
 
-func (r *Runner) saveResult(instrument string, month common.Month, strategy modular.Builder, metrics *backtesting.Metrics) error {
-	strategyStr := modular.ToJSON(strategy)
+	return results, nil
+}
 
-	run := &run{
-		Key:        r.db.ComputeKey(instrument, month.String(), strategyStr),
-		Instrument: instrument,
-		TimeRange:  month.String(),
-		Strategy:   strategyStr,
-		Metrics:    *metrics,
+// monthRunKey returns the key of the run of a month of a job.
+// Single month jobs keep the key of the job, so that their results are found by their key.
+func monthRunKey(j *job, month common.Month) string {
+	if j.TimeRange == month.String() {
+		return j.Key
 	}
 
-	if err := r.db.SaveRun(run); err != nil {
-		return fmt.Errorf("failed to save run: %w", err)
+	hash := md5.New()
+	hash.Write([]byte(fmt.Sprintf("%s:%s", j.Key, month.String())))
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+func newRuns(j *job, settings *Settings, results []*monthResult) ([]*run, [][]*backtesting.Trade) {
+	runs := make([]*run, 0, len(results))
+	trades := make([][]*backtesting.Trade, 0, len(results))
+
+	for _, result := range results {
+		month, err := common.ParseMonth(result.TimeRange)
+		if err != nil {
+			panic(err) // Results are produced by backtest
+		}
+
+		runs = append(runs, &run{
+			Key:           monthRunKey(j, month),
+			Instrument:    j.Instrument,
+			TimeRange:     result.TimeRange,
+			RunRange:      j.TimeRange,
+			Strategy:      j.Strategy,
+			StrategyKind:  j.StrategyKind,
+			DataSource:    string(settings.DataSource),
+			BrokerConfig:  settings.brokerConfigJSON(),
+			Settings:      settings.Identity(),
+			EngineVersion: j.EngineVersion,
+			Fingerprint:   j.Fingerprint,
+			Metrics:       *result.Metrics,
+		})
+		trades = append(trades, result.Trades)
 	}
 
-	return nil
+	return runs, trades
 }
@@ -0,0 +1,260 @@
+package runner
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations are applied in order, migration i brings the schema to version i+1.
+// Never edit a released migration, append a new one instead.
+var migrations = []string{
+	// 1: runs
+	`
+    CREATE TABLE IF NOT EXISTS runs (
+        -- Config
+        key TEXT PRIMARY KEY,                -- Unique hash for config
+        instrument TEXT NOT NULL,            -- e.g., EURUSD
+        time_range TEXT NOT NULL,             -- e.g., 202501
+        strategy TEXT NOT NULL,               -- Serialized strategy description (JSON)
+
+        -- Metrics
+        total_trades INTEGER NOT NULL,        -- Total trades
+        win_rate REAL NOT NULL,               -- % of winning trades
+        net_pnl REAL NOT NULL,                 -- Net profit/loss in base currency
+        profit_factor REAL NOT NULL,           -- Gross profit / gross loss
+        max_drawdown_pct REAL NOT NULL,        -- % from peak equity
+        expected_value_r REAL NOT NULL,        -- Avg R-multiple return
+        avg_trade_duration_seconds INTEGER NOT NULL, -- Duration in seconds
+        long_trades INTEGER NOT NULL,          -- Count of long trades
+        short_trades INTEGER NOT NULL          -- Count of short trades
+    );`,
+
+	// 2: significance of runs
+	`
+    ALTER TABLE runs ADD COLUMN t_stat REAL NOT NULL DEFAULT 0;                      -- t-statistic of mean R-multiple
+    ALTER TABLE runs ADD COLUMN p_value REAL NOT NULL DEFAULT 1;                     -- Two-sided p-value of t_stat
+    ALTER TABLE runs ADD COLUMN win_rate_ci_low REAL NOT NULL DEFAULT 0;             -- Bootstrap CI of win rate (%)
+    ALTER TABLE runs ADD COLUMN win_rate_ci_high REAL NOT NULL DEFAULT 0;
+    ALTER TABLE runs ADD COLUMN expectancy_ci_low REAL NOT NULL DEFAULT 0;           -- Bootstrap CI of mean R-multiple
+    ALTER TABLE runs ADD COLUMN expectancy_ci_high REAL NOT NULL DEFAULT 0;
+    ALTER TABLE runs ADD COLUMN min_track_record_length REAL NOT NULL DEFAULT 9e999; -- Min number of trades for significance, +Inf if unknown
+    ALTER TABLE runs ADD COLUMN significant INTEGER NOT NULL DEFAULT 0;              -- 1 if mean R-multiple is distinguishable from zero
+    `,
+
+	// 3: trades of runs
+	`
+    CREATE TABLE trades (
+        run_key TEXT NOT NULL,                -- Key of the run (runs.key)
+        trade_index INTEGER NOT NULL,         -- Order of the trade in the run
+
+        direction TEXT NOT NULL,              -- long or short
+        open_time INTEGER NOT NULL,           -- Unix milliseconds
+        close_time INTEGER NOT NULL,          -- Unix milliseconds
+        open_price REAL NOT NULL,
+        close_price REAL NOT NULL,
+        stop_loss REAL NOT NULL,
+        take_profit REAL NOT NULL,
+        quantity INTEGER NOT NULL,            -- Number of lots
+        pnl REAL NOT NULL,                    -- Profit/loss in base currency
+        r_multiple REAL NOT NULL,             -- PnL / initial risk
+        reason TEXT NOT NULL,                 -- Reason of the order
+        reason_details TEXT,                  -- Structured reason (JSON), e.g. strategy state at entry
+
+        PRIMARY KEY (run_key, trade_index)
+    );`,
+
+	// 4: strategy kind
+	`
+    ALTER TABLE runs ADD COLUMN strategy_kind TEXT NOT NULL DEFAULT 'modular'; -- e.g., modular, expression
+    CREATE INDEX runs_strategy_kind ON runs (strategy_kind);
+    `,
+
+	// 5: run settings
+	// Keys now include the settings, so runs saved before this version are no longer found by the cache.
+	`
+    ALTER TABLE runs ADD COLUMN data_source TEXT NOT NULL DEFAULT 'histdata'; -- e.g., histdata, dukascopy
+    ALTER TABLE runs ADD COLUMN broker_config TEXT NOT NULL DEFAULT '';       -- Serialized broker config and cost model (JSON)
+    `,
+
+	// 6: jobs
+	`
+    CREATE TABLE jobs (
+        key TEXT PRIMARY KEY,                 -- Key of the run (runs.key)
+        instrument TEXT NOT NULL,
+        time_range TEXT NOT NULL,
+        strategy TEXT NOT NULL,               -- Strategy identity
+        strategy_kind TEXT NOT NULL,
+        settings TEXT NOT NULL,               -- Serialized run settings (JSON)
+
+        status TEXT NOT NULL,                 -- pending, running, done, failed
+        error TEXT NOT NULL DEFAULT '',       -- Error of the last attempt
+        attempts INTEGER NOT NULL DEFAULT 0,
+        submitted_at INTEGER NOT NULL,        -- Unix milliseconds
+        started_at INTEGER,                   -- Unix milliseconds, of the last attempt
+        finished_at INTEGER                   -- Unix milliseconds, of the last attempt
+    );
+    CREATE INDEX jobs_status ON jobs (status);
+    `,
+
+	// 7: job leases of distributed workers
+	`
+    ALTER TABLE jobs ADD COLUMN worker TEXT NOT NULL DEFAULT '';   -- Worker running the job, empty when run locally
+    ALTER TABLE jobs ADD COLUMN lease_expires_at INTEGER;          -- Unix milliseconds, the job is reassigned after it
+    `,
+
+	// 8: engine fingerprint, part of the key
+	// Runs and jobs of previous versions keep version 0, and are never found again (see cmd/results to purge them).
+	`
+    ALTER TABLE runs ADD COLUMN engine_version INTEGER NOT NULL DEFAULT 0;
+    ALTER TABLE runs ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';   -- Engine version, broker config and data checksum
+    CREATE INDEX runs_engine_version ON runs (engine_version);
+
+    ALTER TABLE jobs ADD COLUMN engine_version INTEGER NOT NULL DEFAULT 0;
+    ALTER TABLE jobs ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';
+    `,
+
+	// 9: continuous runs over several months, saved per month
+	`
+    ALTER TABLE runs ADD COLUMN run_range TEXT NOT NULL DEFAULT '';   -- Time range of the run that produced the month, empty before version 9
+    `,
+
+	// 10: optimisation studies
+	`
+    CREATE TABLE studies (
+        name TEXT PRIMARY KEY,
+        optimizer TEXT NOT NULL,              -- e.g., tpe
+        objective TEXT NOT NULL,              -- e.g., sharpe
+        seed INTEGER NOT NULL,
+        created_at INTEGER NOT NULL           -- Unix milliseconds
+    );
+
+    CREATE TABLE trials (
+        study TEXT NOT NULL,                  -- Name of the study (studies.name)
+        trial_index INTEGER NOT NULL,         -- Order of the proposal in the study
+        combo_id TEXT NOT NULL,               -- Stable ID of the combination
+        combo TEXT NOT NULL,                  -- Serialized combination (JSON)
+        status TEXT NOT NULL,                 -- pending, done, failed
+        value REAL,                           -- Objective, once done
+
+        PRIMARY KEY (study, trial_index)
+    );
+    CREATE UNIQUE INDEX trials_combo ON trials (study, combo_id);
+    `,
+
+	// 11: why trades were closed
+	`
+    ALTER TABLE trades ADD COLUMN close_reason TEXT NOT NULL DEFAULT ''; -- e.g., stop loss, take profit, max holding time
+    `,
+
+	// 12: settings of runs, including the warm-up missing from the broker config
+	// Runs are matched with the jobs that produced them, runs without a single matching job keep empty settings.
+	`
+    ALTER TABLE runs ADD COLUMN settings TEXT NOT NULL DEFAULT '';   -- Serialized run settings (JSON)
+    CREATE INDEX runs_settings ON runs (settings);
+
+    UPDATE runs SET settings = (
+        SELECT jobs.settings FROM jobs
+        WHERE jobs.instrument = runs.instrument AND jobs.time_range = runs.run_range
+            AND jobs.strategy = runs.strategy AND jobs.fingerprint = runs.fingerprint
+    )
+    WHERE (
+        SELECT COUNT(DISTINCT jobs.settings) FROM jobs
+        WHERE jobs.instrument = runs.instrument AND jobs.time_range = runs.run_range
+            AND jobs.strategy = runs.strategy AND jobs.fingerprint = runs.fingerprint
+    ) = 1;
+    `,
+
+	// 13: unknown min track record length of runs older than migration 2
+	// Migration 2 defaulted it to 0 trades, metrics.Significance uses +Inf (9e999 in SQLite) for never significant.
+	`
+    UPDATE runs SET min_track_record_length = 9e999 WHERE min_track_record_length = 0;
+    `,
+
+	// 14: coordinator serving jobs, so that it only leases and waits for the jobs it was submitted
+	`
+    ALTER TABLE jobs ADD COLUMN submission TEXT NOT NULL DEFAULT ''; -- Submission of the coordinator, empty when run locally
+    CREATE INDEX jobs_submission ON jobs (submission, status);
+    `,
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL);`); err != nil {
+		return err
+	}
+
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", version, len(migrations))
+	}
+
+	for ; version < len(migrations); version++ {
+		log.Info("Migrating database to schema version %d", version+1)
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration to version %d failed: %w", version+1, err)
+		}
+
+		if err := setSchemaVersion(tx, version+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func schemaVersion(db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT version FROM schema_version;`).Scan(&version)
+
+	if err == sql.ErrNoRows {
+		// Databases created before schema versioning
+		return legacySchemaVersion(db)
+	}
+
+	return version, err
+}
+
+// legacySchemaVersion detects the schema of a database created before schema versioning.
+func legacySchemaVersion(db *sql.DB) (int, error) {
+	hasRuns, err := hasColumn(db, "runs", "key")
+	if err != nil || !hasRuns {
+		return 0, err
+	}
+
+	hasSignificance, err := hasColumn(db, "runs", "t_stat")
+	if err != nil || !hasSignificance {
+		return 1, err
+	}
+
+	return 2, nil
+}
+
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?;`, table, column).Scan(&count)
+	return count > 0, err
+}
+
+func setSchemaVersion(tx *sql.Tx, version int) error {
+	if _, err := tx.Exec(`DELETE FROM schema_version;`); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?);`, version)
+	return err
+}
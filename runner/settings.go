@@ -0,0 +1,57 @@
+package runner
+
+import (
+	"encoding/json"
+	"time"
+	"trading-bot/brokers/backtesting"
+)
+
+// Settings are the assumptions of a run, besides the strategy and the period.
+// They are part of the cache key, so that results from different assumptions never collide.
+type Settings struct {
+	DataSource backtesting.DataSource `json:"dataSource"`
+	Broker     backtesting.Config     `json:"broker"` // Includes the cost model
+
+	// WarmUp is the duration of data before a run that only fills the history of the trader, without trading.
+	// Omitted when zero, so that the identity of settings without warm-up is unchanged.
+	WarmUp time.Duration `json:"warmUp,omitempty"`
+}
+
+func DefaultSettings() *Settings {
+	return &Settings{
+		DataSource: backtesting.HistData,
+		Broker: backtesting.Config{
+			// For backtesting, we assume a lot size of 1 for simplicity.
+			// In a real broker, this would be the number of units per lot.
+			// Not that using IG broker, EUR/USD Mini has also a size of 1.
+			LotSize: 1,
+
+			// Leverage is the ratio of the amount of capital that a trader must put up to open a position.
+			// For example, if the leverage is 30, it means that for every 1 unit of capital,
+			// the trader can control 30 units of the asset.
+			// This is a common leverage ratio in forex trading.
+			Leverage: 30.0,
+
+			InitialCapital: 100000,
+		},
+	}
+}
+
+// Identity is a stable serialized description of the settings.
+func (s *Settings) Identity() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(data)
+}
+
+func (s *Settings) brokerConfigJSON() string {
+	data, err := json.Marshal(&s.Broker)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(data)
+}
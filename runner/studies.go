@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+	"trading-bot/gridsearch"
+)
+
+// Study is a named optimisation, whose trials are saved so that it can be resumed.
+type Study struct {
+	Name      string
+	Optimizer string // e.g., tpe
+	Objective Objective
+	Seed      int64
+
+	db *Database
+}
+
+// OpenStudy returns the study with this name, creating it if needed.
+// A study can only be resumed with the optimizer, objective and seed it was created with.
+func (r *Runner) OpenStudy(name, optimizer string, objective Objective, seed int64) (*Study, error) {
+	study, err := r.db.FindStudy(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if study == nil {
+		_, err := r.db.db.Exec(`
+        INSERT INTO studies (name, optimizer, objective, seed, created_at)
+        VALUES (?, ?, ?, ?, ?);`,
+			name, optimizer, objective, seed, time.Now().UnixMilli(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create study: %w", err)
+		}
+
+		return &Study{Name: name, Optimizer: optimizer, Objective: objective, Seed: seed, db: r.db}, nil
+	}
+
+	if study.Optimizer != optimizer || study.Objective != objective || study.Seed != seed {
+		return nil, fmt.Errorf("study %s was created with optimizer %s, objective %s and seed %d",
+			name, study.Optimizer, study.Objective, study.Seed)
+	}
+
+	return study, nil
+}
+
+// FindStudy returns the study with this name, nil if it does not exist.
+func (db *Database) FindStudy(name string) (*Study, error) {
+	study := &Study{db: db}
+
+	err := db.db.QueryRow(`SELECT name, optimizer, objective, seed FROM studies WHERE name = ?;`, name).
+		Scan(&study.Name, &study.Optimizer, &study.Objective, &study.Seed)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find study: %w", err)
+	}
+
+	return study, nil
+}
+
+// Trials returns the trials of the study in order of proposal, with the values of the space.
+func (s *Study) Trials(space *gridsearch.ParameterSpace) ([]*gridsearch.Trial, error) {
+	rows, err := s.db.db.Query(`
+    SELECT trial_index, combo, status, value
+    FROM trials
+    WHERE study = ?
+    ORDER BY trial_index;`, s.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trials := []*gridsearch.Trial{}
+	for rows.Next() {
+		var trial gridsearch.Trial
+		var combo string
+		var value sql.NullFloat64
+
+		if err := rows.Scan(&trial.Index, &combo, &trial.Status, &value); err != nil {
+			return nil, err
+		}
+
+		trial.Combo, err = space.ParseCombo([]byte(combo))
+		if err != nil {
+			return nil, fmt.Errorf("trial %d of study %s does not fit the space: %w", trial.Index, s.Name, err)
+		}
+		trial.Value = value.Float64
+
+		trials = append(trials, &trial)
+	}
+
+	return trials, rows.Err()
+}
+
+// SaveTrial inserts or updates a trial.
+func (s *Study) SaveTrial(trial *gridsearch.Trial) error {
+	combo, err := json.Marshal(trial.Combo)
+	if err != nil {
+		return fmt.Errorf("failed to serialize combination: %w", err)
+	}
+
+	var value sql.NullFloat64
+	if trial.Status == gridsearch.TrialStatusDone {
+		value = sql.NullFloat64{Float64: trial.Value, Valid: true}
+	}
+
+	_, err = s.db.db.Exec(`
+    INSERT INTO trials (study, trial_index, combo_id, combo, status, value)
+    VALUES (?, ?, ?, ?, ?, ?)
+    ON CONFLICT (study, trial_index) DO UPDATE SET
+        status = excluded.status,
+        value = excluded.value;`,
+		s.Name, trial.Index, trial.Combo.ID(), string(combo), trial.Status, value,
+	)
+	return err
+}
@@ -1,12 +1,14 @@
 package strategies
 
 import (
+	"fmt"
 	"time"
 	"trading-bot/common"
 	"trading-bot/gridsearch"
 	"trading-bot/traders/modular"
 	"trading-bot/traders/modular/conditions"
 	"trading-bot/traders/modular/indicators"
+	"trading-bot/traders/modular/ordercomputer"
 )
 
 func Breakout(strategy modular.StrategyBuilder) {
@@ -71,27 +73,107 @@ func Breakout(strategy modular.StrategyBuilder) {
 // 4. Optional trend filter
 // - EMA200 trend filter: enabled or disabled
 
-var BreakoutSpace = gridsearch.ParameterSpace{
-	"RSIPeriod":      {7, 14, 21},
-	"RSILower":       {25.0, 30.0, 35.0},
-	"RSIUpper":       {65.0, 70.0, 75.0},
-	"ADXPeriod":      {7, 14, 21},
-	"ADXThreshold":   {15.0, 20.0, 25.0},
-	"ShortEMAPeriod": {5, 8, 10},
-	"LongEMAPeriod":  {20, 30, 50},
-	"TradeDays":      {"TueThu", "MonFri"},
-	// "Session":        {"London", "NewYork", "Both"},
-	"TrendFilter": {true, false},
+// BreakoutSpace returns the parameters of BreakoutGS.
+func BreakoutSpace() (*gridsearch.ParameterSpace, error) {
+	return gridsearch.NewParameterSpace([]*gridsearch.Parameter{
+		gridsearch.IntValues("RSIPeriod", 7, 14, 21),
+		gridsearch.FloatRange("RSILower", 25, 35, 5),
+		gridsearch.FloatRange("RSIUpper", 65, 75, 5),
+		gridsearch.IntValues("ADXPeriod", 7, 14, 21),
+		gridsearch.FloatRange("ADXThreshold", 15, 25, 5),
+		gridsearch.IntValues("ShortEMAPeriod", 5, 8, 10),
+		gridsearch.IntValues("LongEMAPeriod", 20, 30, 50),
+		gridsearch.Categorical("TradeDays", "TueThu", "MonFri"),
+		// gridsearch.Categorical("Session", "London", "NewYork", "Both"),
+		gridsearch.Bool("TrendFilter"),
+	},
+		gridsearch.LessThan("ShortEMAPeriod", "LongEMAPeriod"),
+		gridsearch.LessThan("RSILower", "RSIUpper"),
+	)
+}
+
+// BreakoutGSBuilder returns the trader of a combination of BreakoutSpace, with a fixed risk management.
+func BreakoutGSBuilder(combo gridsearch.Combo) (modular.Builder, error) {
+	builder := modular.NewBuilder()
+	builder.SetHistorySize(250)
+
+	if err := BreakoutGS(builder.Strategy(), combo); err != nil {
+		return nil, fmt.Errorf("invalid combination %s: %w", combo.ID(), err)
+	}
+
+	builder.RiskManager().SetStopLoss(
+		ordercomputer.StopLossATR(indicators.ATR(14), 1.0),
+		//ordercomputer.StopLossPipBuffer(3, 15),
+	).SetTakeProfit(
+		ordercomputer.TakeProfitRatio(2.0),
+	)
+
+	builder.CapitalAllocator().SetAllocator(
+		ordercomputer.CapitalFixed(10),
+	)
+
+	return builder, nil
 }
 
-func BreakoutGS(strategy modular.StrategyBuilder, c gridsearch.Combo) {
+// breakoutParameters are the values of a combination of BreakoutSpace.
+type breakoutParameters struct {
+	rsiPeriod, adxPeriod, shortEMAPeriod, longEMAPeriod int
+	rsiLower, rsiUpper, adxThreshold                    float64
+	tradeDays                                           string
+	trendFilter                                         bool
+}
+
+func parseBreakoutParameters(c gridsearch.Combo) (*breakoutParameters, error) {
+	p := &breakoutParameters{}
+
+	for key, value := range map[string]*int{
+		"RSIPeriod":      &p.rsiPeriod,
+		"ADXPeriod":      &p.adxPeriod,
+		"ShortEMAPeriod": &p.shortEMAPeriod,
+		"LongEMAPeriod":  &p.longEMAPeriod,
+	} {
+		var err error
+		if *value, err = c.Int(key); err != nil {
+			return nil, err
+		}
+	}
+
+	for key, value := range map[string]*float64{
+		"RSILower":     &p.rsiLower,
+		"RSIUpper":     &p.rsiUpper,
+		"ADXThreshold": &p.adxThreshold,
+	} {
+		var err error
+		if *value, err = c.Float(key); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if p.tradeDays, err = c.String("TradeDays"); err != nil {
+		return nil, err
+	}
+	if p.trendFilter, err = c.Bool("TrendFilter"); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func BreakoutGS(strategy modular.StrategyBuilder, c gridsearch.Combo) error {
+	p, err := parseBreakoutParameters(c)
+	if err != nil {
+		return err
+	}
 
 	var tradeDays []time.Weekday
-	switch c.String("TradeDays") {
+	switch p.tradeDays {
 	case "TueThu":
 		tradeDays = []time.Weekday{time.Tuesday, time.Wednesday, time.Thursday}
 	case "MonFri":
 		tradeDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	default:
+		return fmt.Errorf("unknown trade days: %s", p.tradeDays)
 	}
 
 	strategy.SetFilter(conditions.And(
@@ -104,14 +186,14 @@ func BreakoutGS(strategy modular.StrategyBuilder, c gridsearch.Combo) {
 		conditions.Session(common.LondonSession),
 		conditions.Session(common.NYSession),
 
-		conditions.IndicatorRange(indicators.RSI(c.Int("RSIPeriod")), c.Float("RSILower"), c.Float("RSIUpper")),
-		conditions.Threshold(indicators.ADX(c.Int("ADXPeriod")), c.Float("ADXThreshold"), conditions.Above),
+		conditions.IndicatorRange(indicators.RSI(p.rsiPeriod), p.rsiLower, p.rsiUpper),
+		conditions.Threshold(indicators.ADX(p.adxPeriod), p.adxThreshold, conditions.Above),
 	))
 
 	var trendLong conditions.Condition
 	var trendShort conditions.Condition
 
-	if c.Bool("TrendFilter") {
+	if p.trendFilter {
 		trendLong = conditions.PriceThreshold(indicators.EMA(200), conditions.Above)
 		trendShort = conditions.PriceThreshold(indicators.EMA(200), conditions.Below)
 	} else {
@@ -123,8 +205,8 @@ func BreakoutGS(strategy modular.StrategyBuilder, c gridsearch.Combo) {
 		conditions.And(
 			trendLong,
 			conditions.CrossOver(
-				indicators.EMA(c.Int("LongEMAPeriod")),
-				indicators.EMA(c.Int("ShortEMAPeriod")),
+				indicators.EMA(p.longEMAPeriod),
+				indicators.EMA(p.shortEMAPeriod),
 				conditions.CrossOverUp,
 			),
 		),
@@ -134,10 +216,12 @@ func BreakoutGS(strategy modular.StrategyBuilder, c gridsearch.Combo) {
 		conditions.And(
 			trendShort,
 			conditions.CrossOver(
-				indicators.EMA(c.Int("LongEMAPeriod")),
-				indicators.EMA(c.Int("ShortEMAPeriod")),
+				indicators.EMA(p.longEMAPeriod),
+				indicators.EMA(p.shortEMAPeriod),
 				conditions.CrossOverDown,
 			),
 		),
 	)
+
+	return nil
 }
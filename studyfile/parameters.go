@@ -0,0 +1,149 @@
+package studyfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"trading-bot/gridsearch"
+)
+
+// parameterFile is the JSON format of a parameter, with either explicit values or a range:
+//
+//	{"name": "RSIPeriod", "type": "int", "values": [7, 14, 21]}
+//	{"name": "RSILower", "type": "float", "min": 25, "max": 35, "step": 5}
+//	{"name": "LongEMAPeriod", "type": "int", "min": 20, "max": 200, "count": 6, "scale": "log"}
+//	{"name": "TradeDays", "type": "categorical", "values": ["TueThu", "MonFri"], "choices": {"TueThu": [...], "MonFri": [...]}}
+//	{"name": "TrendFilter", "type": "bool"}
+type parameterFile struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"` // int, float, categorical or bool
+	Values []json.RawMessage `json:"values"`
+
+	Min   *float64 `json:"min"`
+	Max   *float64 `json:"max"`
+	Step  float64  `json:"step"`
+	Count int      `json:"count"` // Of a log scale
+	Scale string   `json:"scale"` // linear (by step) or log (count values)
+
+	// Choices are the JSON substituted for each value of a categorical parameter, instead of the value itself
+	Choices map[string]json.RawMessage `json:"choices"`
+}
+
+func (p *parameterFile) parameter() (*gridsearch.Parameter, error) {
+	kind := gridsearch.ParameterKind(p.Type)
+
+	if p.Choices != nil && kind != gridsearch.ParameterCategorical {
+		return nil, fmt.Errorf("parameter %s: only categorical parameters have choices", p.Name)
+	}
+
+	switch kind {
+	case gridsearch.ParameterInt, gridsearch.ParameterFloat:
+		if p.Values != nil {
+			return p.numberValues(kind)
+		}
+		return p.numberRange(kind)
+
+	case gridsearch.ParameterCategorical:
+		values := make([]string, len(p.Values))
+		for i, raw := range p.Values {
+			if err := json.Unmarshal(raw, &values[i]); err != nil {
+				return nil, fmt.Errorf("parameter %s: categorical values are strings: %w", p.Name, err)
+			}
+		}
+		if p.Choices != nil {
+			if len(values) == 0 {
+				// Values default to the choices, in a stable order
+				for value := range p.Choices {
+					values = append(values, value)
+				}
+				slices.Sort(values)
+			}
+			for _, value := range values {
+				if _, ok := p.Choices[value]; !ok {
+					return nil, fmt.Errorf("parameter %s: no choice for value %s", p.Name, value)
+				}
+			}
+		}
+		return gridsearch.Categorical(p.Name, values...), nil
+
+	case gridsearch.ParameterBool:
+		return gridsearch.Bool(p.Name), nil
+
+	default:
+		return nil, fmt.Errorf("parameter %s: unknown type %q", p.Name, p.Type)
+	}
+}
+
+func (p *parameterFile) numberValues(kind gridsearch.ParameterKind) (*gridsearch.Parameter, error) {
+	values := make([]float64, len(p.Values))
+	for i, raw := range p.Values {
+		if err := json.Unmarshal(raw, &values[i]); err != nil {
+			return nil, fmt.Errorf("parameter %s: %s values are numbers: %w", p.Name, kind, err)
+		}
+	}
+
+	if kind == gridsearch.ParameterFloat {
+		return gridsearch.FloatValues(p.Name, values...), nil
+	}
+
+	ints := make([]int, len(values))
+	for i, value := range values {
+		if value != math.Trunc(value) {
+			return nil, fmt.Errorf("parameter %s: %g is not an int", p.Name, value)
+		}
+		ints[i] = int(value)
+	}
+	return gridsearch.IntValues(p.Name, ints...), nil
+}
+
+func (p *parameterFile) numberRange(kind gridsearch.ParameterKind) (*gridsearch.Parameter, error) {
+	if p.Min == nil || p.Max == nil {
+		return nil, fmt.Errorf("parameter %s: values, or min and max, are required", p.Name)
+	}
+	min, max := *p.Min, *p.Max
+
+	if kind == gridsearch.ParameterInt && (min != math.Trunc(min) || max != math.Trunc(max) || p.Step != math.Trunc(p.Step)) {
+		return nil, fmt.Errorf("parameter %s: int range of non-integers", p.Name)
+	}
+
+	switch p.Scale {
+	case "", "linear":
+		if kind == gridsearch.ParameterInt {
+			return gridsearch.IntRange(p.Name, int(min), int(max), int(p.Step)), nil
+		}
+		return gridsearch.FloatRange(p.Name, min, max, p.Step), nil
+
+	case "log":
+		if kind == gridsearch.ParameterInt {
+			return gridsearch.IntLogRange(p.Name, int(min), int(max), p.Count), nil
+		}
+		return gridsearch.FloatLogRange(p.Name, min, max, p.Count), nil
+
+	default:
+		return nil, fmt.Errorf("parameter %s: unknown scale %q", p.Name, p.Scale)
+	}
+}
+
+var constraintPattern = regexp.MustCompile(`^\s*(\w+)\s*(<=|<|>=|>)\s*(\w+)\s*$`)
+
+// parseConstraint parses a comparison of two number parameters, e.g., ShortEMAPeriod < LongEMAPeriod.
+func parseConstraint(value string) (*gridsearch.Constraint, error) {
+	match := constraintPattern.FindStringSubmatch(value)
+	if match == nil {
+		return nil, fmt.Errorf("invalid constraint %q, expected a comparison of two parameters (e.g., A < B)", value)
+	}
+
+	a, operator, b := match[1], match[2], match[3]
+	switch operator {
+	case "<":
+		return gridsearch.LessThan(a, b), nil
+	case "<=":
+		return gridsearch.LessOrEqual(a, b), nil
+	case ">":
+		return gridsearch.LessThan(b, a), nil
+	default:
+		return gridsearch.LessOrEqual(b, a), nil
+	}
+}
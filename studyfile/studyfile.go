@@ -0,0 +1,269 @@
+// Package studyfile declares gridsearch experiments in JSON files: instruments, months, a modular strategy template
+// with parameter placeholders, the parameter space, the sampler or optimizer, and the objectives.
+package studyfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+	"trading-bot/common"
+	"trading-bot/gridsearch"
+	"trading-bot/runner"
+	"trading-bot/traders"
+	"trading-bot/traders/modular"
+	"trading-bot/walkforward"
+)
+
+// Study is an experiment of the gridsearch command.
+type Study struct {
+	Name        string // Of the optimizer study, resumed if it exists
+	Instruments []string
+	From, To    common.Month
+	Continuous  bool          // Run each strategy over all months at once, instead of month by month
+	WarmUp      time.Duration // Data before each run used to fill the history, without trading
+
+	Sampler gridsearch.Sampler
+	Budget  int // Number of sampled strategies, ignored by the grid sampler
+	Seed    int64
+
+	Optimizer string // tpe, or empty to sample
+	Trials    int    // Number of strategies evaluated by the optimizer
+	Batch     int    // Number of strategies proposed at once by the optimizer
+
+	Objective   runner.Objective       // Maximized by the optimizer, or by each walk-forward window
+	Objectives  *runner.MultiObjective // Pareto front of the sampled strategies, nil if not exported
+	WalkForward *WalkForward           // nil without walk-forward analysis
+	Space       *gridsearch.ParameterSpace
+
+	// Build returns the trader of a combination of the space.
+	Build func(combo gridsearch.Combo) (modular.Builder, error)
+}
+
+// WalkForward optimizes on in-sample windows and trades the best strategy on the next months.
+type WalkForward struct {
+	Mode        walkforward.Mode
+	InSample    int // Months
+	OutOfSample int // Months
+}
+
+// OptimizerTPE is the Tree-structured Parzen Estimator optimizer of gridsearch.
+const OptimizerTPE = "tpe"
+
+// Strategy returns the strategy of a combination. It is called for each submission, as strategies may hold state.
+func (s *Study) Strategy(combo gridsearch.Combo) (traders.Strategy, error) {
+	builder, err := s.Build(combo)
+	if err != nil {
+		return nil, err
+	}
+	return traders.NewModularStrategy(builder), nil
+}
+
+func (s *Study) Months() []common.Month {
+	return common.Months(s.From, s.To)
+}
+
+// Validate returns an error if the study combines modes that gridsearch cannot run together:
+// the optimizer, the walk-forward analysis and the Pareto front of the objectives are exclusive.
+func (s *Study) Validate() error {
+	var modes []string
+	if s.Optimizer != "" {
+		modes = append(modes, "optimizer")
+	}
+	if s.WalkForward != nil {
+		modes = append(modes, "walk-forward analysis")
+	}
+	if s.Objectives != nil {
+		modes = append(modes, "objectives")
+	}
+
+	if len(modes) > 1 {
+		return fmt.Errorf("cannot combine %s", strings.Join(modes, " and "))
+	}
+	return nil
+}
+
+// file is the JSON format of a study. Missing fields have the defaults of the gridsearch flags.
+type file struct {
+	Name        string   `json:"name"`
+	Instruments []string `json:"instruments"`
+	Months      string   `json:"months"` // e.g., 2023-01..2023-06
+	Continuous  bool     `json:"continuous"`
+	WarmUp      string   `json:"warmUp"` // e.g., 72h
+
+	Sampler string `json:"sampler"`
+	Budget  int    `json:"budget"`
+	Seed    *int64 `json:"seed"`
+
+	Optimizer string `json:"optimizer"`
+	Trials    int    `json:"trials"`
+	Batch     int    `json:"batch"`
+
+	Objective   string           `json:"objective"`
+	Objectives  string           `json:"objectives"` // e.g., expectancy:2,maxDrawdown,trades>=30
+	WalkForward *walkForwardFile `json:"walkForward"`
+
+	Parameters  []*parameterFile `json:"parameters"`
+	Constraints []string         `json:"constraints"` // e.g., ShortEMAPeriod < LongEMAPeriod
+	Template    json.RawMessage  `json:"template"`    // Modular trader JSON, with placeholders
+}
+
+type walkForwardFile struct {
+	Mode        string `json:"mode"`
+	InSample    int    `json:"inSample"`
+	OutOfSample int    `json:"outOfSample"`
+}
+
+func Load(path string) (*Study, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read study file: %w", err)
+	}
+
+	study, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid study file %s: %w", path, err)
+	}
+	return study, nil
+}
+
+// Parse parses a study, and checks that its template builds a trader with a combination of the space.
+func Parse(data []byte) (*Study, error) {
+	f := &file{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+
+	study := &Study{
+		Name:        f.Name,
+		Instruments: f.Instruments,
+		Continuous:  f.Continuous,
+		Budget:      f.Budget,
+		Seed:        1,
+		Optimizer:   f.Optimizer,
+		Trials:      f.Trials,
+		Batch:       f.Batch,
+	}
+
+	if len(study.Instruments) == 0 {
+		study.Instruments = []string{"EURUSD"}
+	}
+	if study.Budget == 0 {
+		study.Budget = 100
+	}
+	if f.Seed != nil {
+		study.Seed = *f.Seed
+	}
+	if study.Trials == 0 {
+		study.Trials = 100
+	}
+	if study.Batch == 0 {
+		study.Batch = runtime.NumCPU()
+	}
+
+	var err error
+
+	if f.Months == "" {
+		return nil, fmt.Errorf("months are required")
+	}
+	if study.From, study.To, err = common.ParseMonthRange(f.Months); err != nil {
+		return nil, err
+	}
+
+	if f.WarmUp != "" {
+		if study.WarmUp, err = time.ParseDuration(f.WarmUp); err != nil {
+			return nil, fmt.Errorf("invalid warm-up: %w", err)
+		}
+	}
+
+	if f.Sampler == "" {
+		f.Sampler = string(gridsearch.SamplerGrid)
+	}
+	if study.Sampler, err = gridsearch.ParseSampler(f.Sampler); err != nil {
+		return nil, err
+	}
+
+	switch study.Optimizer {
+	case "":
+	case OptimizerTPE:
+		if study.Name == "" {
+			return nil, fmt.Errorf("the %s optimizer requires a study name", study.Optimizer)
+		}
+	default:
+		return nil, fmt.Errorf("unknown optimizer: %s", study.Optimizer)
+	}
+
+	if f.Objective == "" {
+		f.Objective = string(runner.ObjectiveSharpe)
+	}
+	if study.Objective, err = runner.ParseObjective(f.Objective); err != nil {
+		return nil, err
+	}
+
+	if f.Objectives != "" {
+		if study.Objectives, err = runner.ParseMultiObjective(f.Objectives); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.WalkForward != nil {
+		study.WalkForward = &WalkForward{InSample: f.WalkForward.InSample, OutOfSample: f.WalkForward.OutOfSample}
+		if study.WalkForward.Mode, err = walkforward.ParseMode(f.WalkForward.Mode); err != nil {
+			return nil, err
+		}
+		if len(study.Instruments) > 1 {
+			return nil, fmt.Errorf("walk-forward analysis supports a single instrument")
+		}
+	}
+
+	if err := study.Validate(); err != nil {
+		return nil, err
+	}
+
+	parameters := make([]*gridsearch.Parameter, len(f.Parameters))
+	choices := make(map[string]map[string]json.RawMessage)
+	for i, p := range f.Parameters {
+		if parameters[i], err = p.parameter(); err != nil {
+			return nil, err
+		}
+		if p.Choices != nil {
+			choices[p.Name] = p.Choices
+		}
+	}
+
+	constraints := make([]*gridsearch.Constraint, len(f.Constraints))
+	for i, c := range f.Constraints {
+		if constraints[i], err = parseConstraint(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if study.Space, err = gridsearch.NewParameterSpace(parameters, constraints...); err != nil {
+		return nil, err
+	}
+
+	if len(f.Template) == 0 {
+		return nil, fmt.Errorf("template is required")
+	}
+	t, err := parseTemplate(f.Template, study.Space, choices)
+	if err != nil {
+		return nil, err
+	}
+	study.Build = t.build
+
+	// Errors of the template are reported before any submission
+	combos, err := study.Space.Sample(gridsearch.SamplerRandom, 1, study.Seed)
+	if err != nil {
+		return nil, err
+	}
+	if len(combos) == 0 {
+		return nil, fmt.Errorf("no combination satisfies the constraints")
+	}
+	if _, err := study.Build(combos[0]); err != nil {
+		return nil, err
+	}
+
+	return study, nil
+}
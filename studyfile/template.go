@@ -0,0 +1,190 @@
+package studyfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"trading-bot/gridsearch"
+	"trading-bot/traders/modular"
+)
+
+// template is a modular trader JSON with placeholders, replaced by the values of a combination:
+//   - "${Name}" is replaced by the value of the parameter, keeping its type, or by its choice for a categorical parameter
+//   - "${Name}" inside a longer string is replaced by the text of the value
+//   - {"$if": "Name", "then": ..., "else": ...} is replaced by then or else, by the value of a bool parameter
+type template struct {
+	root    any
+	space   *gridsearch.ParameterSpace
+	choices map[string]map[string]json.RawMessage
+}
+
+var placeholderPattern = regexp.MustCompile(`\$\{(\w+)\}`)
+
+func parseTemplate(data json.RawMessage, space *gridsearch.ParameterSpace, choices map[string]map[string]json.RawMessage) (*template, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	t := &template{space: space, choices: choices}
+	if err := decoder.Decode(&t.root); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	used := make(map[string]bool)
+	if err := t.check(t.root, used); err != nil {
+		return nil, err
+	}
+
+	// An unused parameter would run the same strategy several times
+	for _, name := range space.Names() {
+		if !used[name] {
+			return nil, fmt.Errorf("parameter %s is not used by the template", name)
+		}
+	}
+
+	return t, nil
+}
+
+// check verifies that the placeholders refer to parameters of the space, and collects them.
+func (t *template) check(node any, used map[string]bool) error {
+	switch node := node.(type) {
+	case string:
+		for _, match := range placeholderPattern.FindAllStringSubmatch(node, -1) {
+			if t.space.Parameter(match[1]) == nil {
+				return fmt.Errorf("template placeholder %s: unknown parameter", match[0])
+			}
+			used[match[1]] = true
+		}
+
+	case []any:
+		for _, child := range node {
+			if err := t.check(child, used); err != nil {
+				return err
+			}
+		}
+
+	case map[string]any:
+		if name, ok := node["$if"]; ok {
+			parameter, ok := name.(string)
+			if !ok || t.space.Parameter(parameter) == nil || t.space.Parameter(parameter).Kind != gridsearch.ParameterBool {
+				return fmt.Errorf("template $if %v: not a bool parameter", name)
+			}
+			if _, ok := node["then"]; !ok {
+				return fmt.Errorf("template $if %s: then is required", parameter)
+			}
+			if _, ok := node["else"]; !ok {
+				return fmt.Errorf("template $if %s: else is required", parameter)
+			}
+			if len(node) != 3 {
+				return fmt.Errorf("template $if %s: only then and else are allowed", parameter)
+			}
+			used[parameter] = true
+		}
+
+		for _, key := range names(node) {
+			if err := t.check(node[key], used); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// build returns the trader of the template with the values of a combination.
+func (t *template) build(combo gridsearch.Combo) (modular.Builder, error) {
+	if err := t.space.Validate(combo); err != nil {
+		return nil, fmt.Errorf("invalid combination %s: %w", combo.ID(), err)
+	}
+
+	node, err := t.substitute(t.root, combo)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize trader: %w", err)
+	}
+
+	builder, err := modular.FromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trader of combination %s: %w", combo.ID(), err)
+	}
+	return builder, nil
+}
+
+func (t *template) substitute(node any, combo gridsearch.Combo) (any, error) {
+	switch node := node.(type) {
+	case string:
+		if match := placeholderPattern.FindStringSubmatch(node); match != nil && match[0] == node {
+			return t.value(match[1], combo)
+		}
+		return placeholderPattern.ReplaceAllStringFunc(node, func(placeholder string) string {
+			return fmt.Sprintf("%v", combo[placeholderPattern.FindStringSubmatch(placeholder)[1]])
+		}), nil
+
+	case []any:
+		result := make([]any, len(node))
+		for i, child := range node {
+			var err error
+			if result[i], err = t.substitute(child, combo); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+
+	case map[string]any:
+		if name, ok := node["$if"]; ok {
+			if combo[name.(string)] == true {
+				return t.substitute(node["then"], combo)
+			}
+			return t.substitute(node["else"], combo)
+		}
+
+		result := make(map[string]any, len(node))
+		for key, child := range node {
+			var err error
+			if result[key], err = t.substitute(child, combo); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+
+	default:
+		return node, nil
+	}
+}
+
+// value returns the value of a parameter, or its choice.
+func (t *template) value(name string, combo gridsearch.Combo) (any, error) {
+	value := combo[name]
+
+	choices, ok := t.choices[name]
+	if !ok {
+		return value, nil
+	}
+
+	choice, ok := choices[value.(string)]
+	if !ok {
+		// Checked when parsing the parameter
+		return nil, fmt.Errorf("parameter %s: no choice for value %v", name, value)
+	}
+
+	var result any
+	if err := json.Unmarshal(choice, &result); err != nil {
+		return nil, fmt.Errorf("parameter %s: invalid choice %s: %w", name, value, err)
+	}
+	return result, nil
+}
+
+// names returns the sorted keys of a map, for stable errors.
+func names[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
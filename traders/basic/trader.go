@@ -9,6 +9,9 @@ var log = common.NewLogger("traders/basic")
 
 func Setup(broker brokers.Broker) {
 	broker.RegisterMarketDataCallback(brokers.Timeframe1Minute, func(candle brokers.Candle) {
+		if candle.WarmUp {
+			return
+		}
 
 		// Example logic: if the candle closed higher than it opened, place a long order
 		if candle.Close > candle.Open {
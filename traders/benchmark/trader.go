@@ -0,0 +1,128 @@
+package benchmark
+
+import (
+	"math"
+	"math/rand"
+	"time"
+	"trading-bot/brokers"
+	"trading-bot/common"
+)
+
+var log = common.NewLogger("traders/benchmark")
+
+type BuyAndHoldConfig struct {
+	// GapCloses are the times of the last candles before the data gaps.
+	// The backtesting broker cancels positions on data gaps, and drops their results:
+	// the position is closed on these candles instead, and taken again on the next usable candle.
+	GapCloses map[time.Time]bool
+}
+
+// SetupBuyAndHold buys with the whole (unleveraged) capital on the first usable candle and holds until the end of the test,
+// except across data gaps.
+func SetupBuyAndHold(broker brokers.Broker, config *BuyAndHoldConfig) {
+	var position brokers.Position
+
+	broker.RegisterMarketDataCallback(brokers.Timeframe1Minute, func(candle brokers.Candle) {
+		if !candle.Usable || candle.WarmUp {
+			return
+		}
+
+		gap := config.GapCloses[broker.GetCurrentTime()]
+		open := position != nil && !position.Closed() && !position.Canceled()
+
+		if open && gap {
+			if err := broker.ClosePosition(position, "data gap"); err != nil {
+				log.Error("Failed to close position: %v", err)
+			}
+			return
+		}
+		if open || gap {
+			return
+		}
+
+		lotValue := float64(broker.GetLotSize()) * candle.Close
+		quantity := int(math.Floor(broker.GetCapital() / lotValue))
+		if quantity <= 0 {
+			return
+		}
+
+		order := &brokers.Order{
+			Direction:  brokers.PositionDirectionLong,
+			Quantity:   quantity,
+			StopLoss:   0,           // Never triggered
+			TakeProfit: math.Inf(1), // Never triggered
+			Reason:     "BuyAndHold",
+		}
+
+		pos, err := broker.PlaceOrder(order)
+		if err != nil {
+			log.Error("Failed to place order: %v", err)
+			return
+		}
+
+		position = pos
+	})
+}
+
+// OrderTemplate describes the direction, stop, target and sizing of an order, relative to its entry price.
+type OrderTemplate struct {
+	Direction      brokers.PositionDirection
+	StopDistance   float64 // Distance between entry price and stop loss
+	TargetDistance float64 // Distance between entry price and take profit
+	Quantity       int
+}
+
+type RandomEntryConfig struct {
+	// Probability to enter a position on each usable candle when no position is open.
+	Probability float64
+
+	// Templates are picked at random to set the direction, stop, target and sizing of each order,
+	// so that the orders follow the mix of long and short trades of the templates.
+	Templates []OrderTemplate
+
+	// Seed of the random generator, so that runs are reproducible.
+	Seed int64
+}
+
+// SetupRandomEntry enters positions at random times with random templates, one position at a time.
+func SetupRandomEntry(broker brokers.Broker, config *RandomEntryConfig) {
+	rng := rand.New(rand.NewSource(config.Seed))
+	var position brokers.Position
+
+	broker.RegisterMarketDataCallback(brokers.Timeframe1Minute, func(candle brokers.Candle) {
+		if !candle.Usable || candle.WarmUp || len(config.Templates) == 0 {
+			return
+		}
+		if position != nil && !position.Closed() && !position.Canceled() {
+			return
+		}
+		if rng.Float64() >= config.Probability {
+			return
+		}
+
+		template := config.Templates[rng.Intn(len(config.Templates))]
+		entryPrice := candle.Close
+
+		order := &brokers.Order{
+			Direction: template.Direction,
+			Quantity:  template.Quantity,
+			Reason:    "RandomEntry",
+		}
+
+		if template.Direction == brokers.PositionDirectionLong {
+			order.StopLoss = entryPrice - template.StopDistance
+			order.TakeProfit = entryPrice + template.TargetDistance
+		} else {
+			order.StopLoss = entryPrice + template.StopDistance
+			order.TakeProfit = entryPrice - template.TargetDistance
+		}
+
+		pos, err := broker.PlaceOrder(order)
+		if err != nil {
+			log.Error("Failed to place order: %v", err)
+			return
+		}
+
+		position = pos
+	})
+}
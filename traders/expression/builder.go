@@ -1,9 +1,11 @@
 package expression
 
 import (
+	"time"
 	"trading-bot/brokers"
 	"trading-bot/traders/expression/conditions"
 	"trading-bot/traders/expression/formatter"
+	"trading-bot/traders/expression/management"
 	"trading-bot/traders/expression/ordercomputer"
 )
 
@@ -29,6 +31,11 @@ func (config *Configuration) Format() *formatter.FormatterNode {
 	)
 }
 
+// HistoryDuration returns the duration of market data that fills the history of the trader.
+func (config *Configuration) HistoryDuration() time.Duration {
+	return time.Duration(config.historySize) * time.Duration(config.timeframe)
+}
+
 func Builder(
 	historySize *historySizeConfiguration,
 	timeframe *timeframeConfiguration,
@@ -150,24 +157,41 @@ func ShortTrigger(value conditions.Condition) *strategyShortTriggerConfiguration
 }
 
 type riskManagerConfiguration struct {
-	stopLoss   *riskManagerStopLossConfiguration
-	takeProfit *riskManagerTakeProfitConfiguration
+	stopLoss       *riskManagerStopLossConfiguration
+	takeProfit     *riskManagerTakeProfitConfiguration
+	stopManagement *riskManagerStopManagementConfiguration
 }
 
 func (config *riskManagerConfiguration) Format() *formatter.FormatterNode {
+	nodes := []*formatter.FormatterNode{
+		config.stopLoss.Format(),
+		config.takeProfit.Format(),
+	}
+	// Only set stop management is formatted, identities of strategies without it are unchanged
+	if config.stopManagement != nil {
+		nodes = append(nodes, config.stopManagement.Format())
+	}
+
 	return formatter.Function(
 		Package,
 		"RiskManager",
-		config.stopLoss.Format(),
-		config.takeProfit.Format(),
+		nodes...,
 	)
 }
 
-func RiskManager(stopLoss *riskManagerStopLossConfiguration, takeProfit *riskManagerTakeProfitConfiguration) *riskManagerConfiguration {
-	return &riskManagerConfiguration{
-		stopLoss,
-		takeProfit,
+// RiskManager sets the initial stop loss and take profit of positions, and optionally the rules moving their stop loss.
+func RiskManager(stopLoss *riskManagerStopLossConfiguration, takeProfit *riskManagerTakeProfitConfiguration, stopManagement ...*riskManagerStopManagementConfiguration) *riskManagerConfiguration {
+	config := &riskManagerConfiguration{
+		stopLoss:   stopLoss,
+		takeProfit: takeProfit,
 	}
+	for _, sm := range stopManagement {
+		if config.stopManagement == nil {
+			config.stopManagement = &riskManagerStopManagementConfiguration{}
+		}
+		config.stopManagement.rules = append(config.stopManagement.rules, sm.rules...)
+	}
+	return config
 }
 
 type riskManagerStopLossConfiguration struct {
@@ -202,6 +226,23 @@ func TakeProfit(value ordercomputer.OrderComputer) *riskManagerTakeProfitConfigu
 	return &riskManagerTakeProfitConfiguration{value}
 }
 
+type riskManagerStopManagementConfiguration struct {
+	rules []management.Rule
+}
+
+func (config *riskManagerStopManagementConfiguration) Format() *formatter.FormatterNode {
+	return formatter.FunctionWithChildren(
+		Package,
+		"StopManagement",
+		config.rules...,
+	)
+}
+
+// StopManagement moves the stop loss of open positions to the tightest stop of the rules, on every candle.
+func StopManagement(rules ...management.Rule) *riskManagerStopManagementConfiguration {
+	return &riskManagerStopManagementConfiguration{rules}
+}
+
 type capitalAllocatorConfiguration struct {
 	capitalAllocator ordercomputer.OrderComputer
 }
@@ -0,0 +1,100 @@
+// Package management moves the stop loss of open positions on every candle: trailing stops and break-even.
+package management
+
+import (
+	"math"
+	"trading-bot/brokers"
+	"trading-bot/traders/expression/context"
+	"trading-bot/traders/expression/formatter"
+)
+
+const Package string = "management"
+
+// Rule computes a stop loss for an open position. The trader keeps the tightest stop of its rules,
+// never loosens it, and closes the position if the stop is beyond the price.
+type Rule interface {
+	formatter.Formatter
+
+	// StopLoss returns the stop loss of the rule, ok is false if the rule does not move the stop yet.
+	StopLoss(ctx context.TraderContext, position brokers.Position) (stopLoss float64, ok bool)
+
+	// HistorySize is the number of candles of the history read by the rule, 0 if it reads none.
+	HistorySize() int
+}
+
+func NewRule(
+	stopLoss func(ctx context.TraderContext, position brokers.Position) (float64, bool),
+	format func() *formatter.FormatterNode,
+) Rule {
+	return newRule(stopLoss, format, 0)
+}
+
+// newRule creates a rule reading historySize candles of the history.
+func newRule(
+	stopLoss func(ctx context.TraderContext, position brokers.Position) (float64, bool),
+	format func() *formatter.FormatterNode,
+	historySize int,
+) Rule {
+	return &rule{
+		stopLoss:    stopLoss,
+		format:      format,
+		historySize: historySize,
+	}
+}
+
+type rule struct {
+	stopLoss    func(ctx context.TraderContext, position brokers.Position) (float64, bool)
+	format      func() *formatter.FormatterNode
+	historySize int
+}
+
+func (r *rule) StopLoss(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+	return r.stopLoss(ctx, position)
+}
+
+func (r *rule) HistorySize() int {
+	return r.historySize
+}
+
+func (r *rule) Format() *formatter.FormatterNode {
+	return r.format()
+}
+
+// Tighter returns true if the stop loss a is closer to the price than b, for the direction of the position.
+func Tighter(direction brokers.PositionDirection, a, b float64) bool {
+	if direction == brokers.PositionDirectionLong {
+		return a > b
+	}
+	return a < b
+}
+
+// sign is 1 for long positions and -1 for short ones: prices in favour of the position are open + sign * distance.
+func sign(direction brokers.PositionDirection) float64 {
+	if direction == brokers.PositionDirectionLong {
+		return 1
+	}
+	return -1
+}
+
+// risk returns the initial risk of the position (1R), as a price distance.
+func risk(position brokers.Position) float64 {
+	return math.Abs(position.OpenPrice() - position.InitialStopLoss())
+}
+
+// profitR returns the profit of the position at the current price, in multiples of its initial risk.
+func profitR(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+	r := risk(position)
+	if r == 0 {
+		return 0, false
+	}
+
+	return (ctx.EntryPrice() - position.OpenPrice()) * sign(position.Direction()) / r, true
+}
+
+// last returns the last value of an indicator, ok is false without values.
+func last(values []float64) (float64, bool) {
+	if len(values) == 0 || math.IsNaN(values[len(values)-1]) {
+		return 0, false
+	}
+	return values[len(values)-1], true
+}
@@ -0,0 +1,55 @@
+package management
+
+import (
+	"fmt"
+	"math"
+	"trading-bot/brokers"
+	"trading-bot/traders/expression/context"
+	"trading-bot/traders/expression/formatter"
+)
+
+// BreakEven moves the stop loss to the open price once the profit reaches afterR multiples of the initial risk.
+func BreakEven(afterR float64) Rule {
+	if afterR <= 0 {
+		panic(fmt.Sprintf("break-even must be after a positive profit, got %.2fR", afterR))
+	}
+
+	return NewRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			profit, ok := profitR(ctx, position)
+			if !ok || profit < afterR {
+				return 0, false
+			}
+			return position.OpenPrice(), true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Function(Package, "BreakEven", formatter.FloatValue(afterR))
+		},
+	)
+}
+
+// StepTrailing moves the stop loss by stepR multiples of the initial risk, each time the profit grows by as much:
+// with a step of 1R, the stop is at break-even after 1R of profit, at +1R after 2R, and so on.
+func StepTrailing(stepR float64) Rule {
+	if stepR <= 0 {
+		panic(fmt.Sprintf("trailing step must be positive, got %.2fR", stepR))
+	}
+
+	return NewRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			profit, ok := profitR(ctx, position)
+			if !ok {
+				return 0, false
+			}
+
+			steps := math.Floor(profit / stepR)
+			if steps < 1 {
+				return 0, false
+			}
+			return position.InitialStopLoss() + sign(position.Direction())*steps*stepR*risk(position), true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Function(Package, "StepTrailing", formatter.FloatValue(stepR))
+		},
+	)
+}
@@ -0,0 +1,88 @@
+package management
+
+import (
+	"fmt"
+	"trading-bot/brokers"
+	"trading-bot/traders/expression/context"
+	"trading-bot/traders/expression/formatter"
+	"trading-bot/traders/expression/indicators"
+
+	"github.com/markcheno/go-talib"
+)
+
+// ATRTrailing trails the stop loss at a multiple of the ATR from the current price.
+func ATRTrailing(atr indicators.Indicator, multiplier float64) Rule {
+	if multiplier <= 0 {
+		panic(fmt.Sprintf("ATR trailing multiplier must be positive, got %.4f", multiplier))
+	}
+
+	return NewRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			currAtr, ok := last(atr.Values(ctx).All())
+			if !ok {
+				return 0, false
+			}
+			return ctx.EntryPrice() - sign(position.Direction())*currAtr*multiplier, true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Function(Package, "ATRTrailing", atr.Format(), formatter.FloatValue(multiplier))
+		},
+	)
+}
+
+// Chandelier trails the stop loss at a multiple of the ATR from the highest high (long) or the lowest low (short)
+// of the last candles.
+func Chandelier(period int, atr indicators.Indicator, multiplier float64) Rule {
+	if period <= 0 {
+		panic(fmt.Sprintf("chandelier period must be positive, got %d", period))
+	}
+	if multiplier <= 0 {
+		panic(fmt.Sprintf("chandelier multiplier must be positive, got %.4f", multiplier))
+	}
+
+	return newRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			history := ctx.HistoricalData()
+			if history.Len() < period {
+				return 0, false
+			}
+
+			currAtr, ok := last(atr.Values(ctx).All())
+			if !ok {
+				return 0, false
+			}
+
+			if position.Direction() == brokers.PositionDirectionLong {
+				return history.GetHighest(period) - currAtr*multiplier, true
+			}
+			return history.GetLowest(period) + currAtr*multiplier, true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Function(Package, "Chandelier", formatter.IntValue(period), atr.Format(), formatter.FloatValue(multiplier))
+		},
+		period,
+	)
+}
+
+// ParabolicSAR trails the stop loss at the parabolic SAR: its acceleration factor grows by acceleration
+// with each new extreme of the trend, up to maximum.
+// The SAR follows the trend of the candles, not the position: it does not move the stop while on the wrong side of the price.
+func ParabolicSAR(acceleration, maximum float64) Rule {
+	if acceleration <= 0 || maximum < acceleration {
+		panic(fmt.Sprintf("invalid parabolic SAR acceleration %.4f and maximum %.4f", acceleration, maximum))
+	}
+
+	return NewRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			history := ctx.HistoricalData()
+			sar, ok := last(talib.Sar(history.GetHighPrices().All(), history.GetLowPrices().All(), acceleration, maximum))
+			if !ok || (sar-ctx.EntryPrice())*sign(position.Direction()) >= 0 {
+				return 0, false
+			}
+			return sar, true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Function(Package, "ParabolicSAR", formatter.FloatValue(acceleration), formatter.FloatValue(maximum))
+		},
+	)
+}
@@ -0,0 +1,41 @@
+package expression
+
+import (
+	"trading-bot/traders/expression/management"
+)
+
+// manageStops moves the stop loss of the open positions to the tightest stop of the management rules, never looser.
+// A stop beyond the price closes the position, e.g., when the price crosses the chandelier.
+func (t *trader) manageStops() {
+	if len(t.stopManagement) == 0 {
+		return
+	}
+
+	for pos := range t.openPositions {
+		stopLoss := pos.StopLoss()
+		var tightest management.Rule
+
+		for _, rule := range t.stopManagement {
+			candidate, ok := rule.StopLoss(t, pos)
+			if ok && management.Tighter(pos.Direction(), candidate, stopLoss) {
+				stopLoss, tightest = candidate, rule
+			}
+		}
+		if tightest == nil {
+			continue
+		}
+
+		err := t.broker.SetStopLoss(pos, stopLoss)
+		if err == nil {
+			continue
+		}
+
+		// The broker rejects a stop beyond the bid or ask of the position
+		log.Debug("Closing position, its stop cannot be moved: %v", err)
+		if err := t.broker.ClosePosition(pos, tightest.Format().Compact()); err != nil {
+			log.Error("Failed to close position: %v", err)
+			continue
+		}
+		delete(t.openPositions, pos)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"trading-bot/traders/expression/conditions"
 	"trading-bot/traders/expression/context"
 	"trading-bot/traders/expression/indicators"
+	"trading-bot/traders/expression/management"
 	"trading-bot/traders/expression/ordercomputer"
 	"trading-bot/traders/tools"
 )
@@ -42,6 +43,7 @@ type trader struct {
 	shortTrigger     conditions.Condition
 	stopLoss         ordercomputer.OrderComputer
 	takeProfit       ordercomputer.OrderComputer
+	stopManagement   []management.Rule
 	capitalAllocator ordercomputer.OrderComputer
 }
 
@@ -66,6 +68,20 @@ func newTrader(broker brokers.Broker, config *Configuration) (*trader, error) {
 		return nil, fmt.Errorf("capital allocator must be set")
 	}
 
+	var stopManagement []management.Rule
+	if config.stopManagement != nil {
+		if len(config.stopManagement.rules) == 0 {
+			return nil, fmt.Errorf("stop management must have rules")
+		}
+		stopManagement = config.stopManagement.rules
+	}
+	for _, rule := range stopManagement {
+		if rule.HistorySize() > config.historySize {
+			return nil, fmt.Errorf("stop management %s reads %d candles, more than the history size %d",
+				rule.Format().Compact(), rule.HistorySize(), config.historySize)
+		}
+	}
+
 	return &trader{
 		broker:           broker,
 		history:          tools.NewHistory(config.historySize),
@@ -76,6 +92,7 @@ func newTrader(broker brokers.Broker, config *Configuration) (*trader, error) {
 		shortTrigger:     config.shortTrigger.value,
 		stopLoss:         config.stopLoss.value,
 		takeProfit:       config.takeProfit.value,
+		stopManagement:   stopManagement,
 		capitalAllocator: config.capitalAllocator,
 	}, nil
 }
@@ -91,6 +108,12 @@ func (t *trader) tick(candle brokers.Candle) {
 
 	t.indicatorCache.Tick()
 
+	if candle.WarmUp {
+		return
+	}
+
+	t.manageStops()
+
 	if !t.filter.Execute(t) {
 		return
 	}
@@ -95,6 +95,10 @@ func newTrader(broker brokers.Broker, config *Config) *trader {
 func (t *trader) tick(candle brokers.Candle) {
 	t.history.AddCandle(candle)
 
+	if candle.WarmUp {
+		return
+	}
+
 	if !t.history.IsUsable() {
 		log.Debug("History is not usable")
 		return
@@ -2,17 +2,29 @@ package modular
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"time"
+	"trading-bot/brokers"
+	"trading-bot/common"
 	"trading-bot/traders/modular/conditions"
 	"trading-bot/traders/modular/formatter"
+	"trading-bot/traders/modular/management"
 	"trading-bot/traders/modular/ordercomputer"
 )
 
 type Builder interface {
 	formatter.Formatter
 	SetHistorySize(size int) Builder
+
+	// SetTimeframeHistorySize sets the number of candles kept for a timeframe read by the strategy (see indicators.OnTimeframe),
+	// the history size by default. Higher timeframes need fewer candles to cover the same period.
+	SetTimeframeHistorySize(timeframe brokers.Timeframe, size int) Builder
+
 	Strategy() StrategyBuilder
 	RiskManager() RiskManagerBuilder
 	CapitalAllocator() CapitalAllocatorBuilder
+	Exit() ExitBuilder
 }
 
 func NewBuilder() Builder {
@@ -28,32 +40,77 @@ type StrategyBuilder interface {
 type RiskManagerBuilder interface {
 	SetStopLoss(computer ordercomputer.OrderComputer) RiskManagerBuilder
 	SetTakeProfit(computer ordercomputer.OrderComputer) RiskManagerBuilder
+
+	// SetStopManagement moves the stop loss of open positions on every candle, to the tightest stop of the rules.
+	SetStopManagement(rules ...management.Rule) RiskManagerBuilder
 }
 
 type CapitalAllocatorBuilder interface {
 	SetAllocator(computer ordercomputer.OrderComputer) CapitalAllocatorBuilder
 }
 
+// ExitBuilder sets the rules closing positions before their stop loss or take profit, evaluated on every candle.
+type ExitBuilder interface {
+	// SetCloseLong closes the long positions when the condition is true.
+	SetCloseLong(condition conditions.Condition) ExitBuilder
+
+	// SetCloseShort closes the short positions when the condition is true.
+	SetCloseShort(condition conditions.Condition) ExitBuilder
+
+	// SetMaxHoldingTime closes the positions open for the duration.
+	SetMaxHoldingTime(duration time.Duration) ExitBuilder
+
+	// SetCloseBeforeWeekend closes the positions, and stops entering, a margin before the weekly close of the market.
+	SetCloseBeforeWeekend(margin time.Duration) ExitBuilder
+
+	// SetCloseAtSessionEnd closes the positions, and stops entering, a margin before the end of the session.
+	SetCloseAtSessionEnd(session *common.Session, margin time.Duration) ExitBuilder
+
+	// SetReverseOnOppositeSignal closes the positions when the trigger of the opposite direction is true.
+	// The trader then enters the opposite direction if its filter allows it.
+	SetReverseOnOppositeSignal(reverse bool) ExitBuilder
+}
+
 type builder struct {
 	historySize      int
+	timeframeSizes   map[brokers.Timeframe]int // History sizes of the higher timeframes, when not the history size
 	filter           conditions.Condition
 	longTrigger      conditions.Condition
 	shortTrigger     conditions.Condition
 	stopLoss         ordercomputer.OrderComputer
 	takeProfit       ordercomputer.OrderComputer
+	stopManagement   []management.Rule
 	capitalAllocator ordercomputer.OrderComputer
+	exit             exitRules
 }
 
 var _ Builder = (*builder)(nil)
 var _ StrategyBuilder = (*builder)(nil)
 var _ RiskManagerBuilder = (*builder)(nil)
 var _ CapitalAllocatorBuilder = (*builder)(nil)
+var _ ExitBuilder = (*builder)(nil)
 
 func (b *builder) SetHistorySize(size int) Builder {
 	b.historySize = size
 	return b
 }
 
+func (b *builder) SetTimeframeHistorySize(timeframe brokers.Timeframe, size int) Builder {
+	if b.timeframeSizes == nil {
+		b.timeframeSizes = make(map[brokers.Timeframe]int)
+	}
+	b.timeframeSizes[timeframe] = size
+	return b
+}
+
+// timeframeHistorySize returns the number of candles kept for a timeframe.
+func (b *builder) timeframeHistorySize(timeframe brokers.Timeframe) int {
+	if size, ok := b.timeframeSizes[timeframe]; ok {
+		return size
+	}
+	return b.historySize
+}
+
 func (b *builder) Strategy() StrategyBuilder {
 	return b
 }
@@ -66,6 +123,10 @@ func (b *builder) CapitalAllocator() CapitalAllocatorBuilder {
 	return b
 }
 
+func (b *builder) Exit() ExitBuilder {
+	return b
+}
+
 func (b *builder) SetFilter(filter conditions.Condition) StrategyBuilder {
 	b.filter = filter
 	return b
@@ -91,14 +152,55 @@ func (b *builder) SetTakeProfit(computer ordercomputer.OrderComputer) RiskManage
 	return b
 }
 
+func (b *builder) SetStopManagement(rules ...management.Rule) RiskManagerBuilder {
+	b.stopManagement = rules
+	return b
+}
+
 func (b *builder) SetAllocator(computer ordercomputer.OrderComputer) CapitalAllocatorBuilder {
 	b.capitalAllocator = computer
 	return b
 }
 
+func (b *builder) SetCloseLong(condition conditions.Condition) ExitBuilder {
+	b.exit.closeLong = condition
+	return b
+}
+
+func (b *builder) SetCloseShort(condition conditions.Condition) ExitBuilder {
+	b.exit.closeShort = condition
+	return b
+}
+
+func (b *builder) SetMaxHoldingTime(duration time.Duration) ExitBuilder {
+	b.exit.maxHoldingTime = duration
+	return b
+}
+
+func (b *builder) SetCloseBeforeWeekend(margin time.Duration) ExitBuilder {
+	b.exit.weekendMargin = &margin
+	return b
+}
+
+func (b *builder) SetCloseAtSessionEnd(session *common.Session, margin time.Duration) ExitBuilder {
+	b.exit.session = session
+	b.exit.sessionMargin = margin
+	return b
+}
+
+func (b *builder) SetReverseOnOppositeSignal(reverse bool) ExitBuilder {
+	b.exit.reverse = reverse
+	return b
+}
+
 func (b *builder) Format() *formatter.FormatterNode {
-	return formatter.Format("ModularTrader",
+	nodes := []*formatter.FormatterNode{
 		formatter.Format(fmt.Sprintf("HistorySize: %d", b.historySize)),
+	}
+	for _, timeframe := range slices.Sorted(maps.Keys(b.timeframeSizes)) {
+		nodes = append(nodes, formatter.Format(fmt.Sprintf("HistorySize %s: %d", timeframe, b.timeframeSizes[timeframe])))
+	}
+	nodes = append(nodes,
 		formatter.FormatWithChildren("Filter", b.filter),
 		formatter.FormatWithChildren("LongTrigger", b.longTrigger),
 		formatter.FormatWithChildren("ShortTrigger", b.shortTrigger),
@@ -106,6 +208,14 @@ func (b *builder) Format() *formatter.FormatterNode {
 		formatter.FormatWithChildren("TakeProfit", b.takeProfit),
 		formatter.FormatWithChildren("CapitalAllocator", b.capitalAllocator),
 	)
+	if len(b.stopManagement) > 0 {
+		nodes = append(nodes, formatter.FormatWithChildren("StopManagement", b.stopManagement...))
+	}
+	if !b.exit.empty() {
+		nodes = append(nodes, b.exit.Format())
+	}
+
+	return formatter.Format("ModularTrader", nodes...)
 }
 
 func Format(b Builder) string {
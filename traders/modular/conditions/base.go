@@ -31,7 +31,15 @@ type condition struct {
 }
 
 func (c *condition) Execute(ctx context.TraderContext) bool {
-	return c.execute(ctx)
+	recorder := ctx.Recorder()
+	if recorder == nil {
+		return c.execute(ctx)
+	}
+
+	recorder.BeginCondition(c.format())
+	result := c.execute(ctx)
+	recorder.EndCondition(result)
+	return result
 }
 
 func (c *condition) Format() *formatter.FormatterNode {
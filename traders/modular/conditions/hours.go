@@ -52,15 +52,9 @@ func Session(session *common.Session) Condition {
 			return formatter.Format(fmt.Sprintf("Session: %s", session.String()))
 		},
 		func() (string, any) {
-			// TODO: more dynamic
-			var sessionName string
-			switch session {
-			case common.LondonSession:
-				sessionName = "london"
-			case common.NYSession:
-				sessionName = "new-york"
-			default:
-				panic(fmt.Sprintf("unknown session: %+v", session))
+			sessionName, err := SessionName(session)
+			if err != nil {
+				panic(err)
 			}
 
 			return "session", sessionName
@@ -75,17 +69,36 @@ func init() {
 			return nil, fmt.Errorf("failed to parse session condition: %w", err)
 		}
 
-		var session *common.Session
-		// TODO: more dynamic
-		switch sessionName {
-		case "london":
-			session = common.LondonSession
-		case "new-york":
-			session = common.NYSession
-		default:
-			return nil, fmt.Errorf("unknown session: %s", sessionName)
+		session, err := ParseSession(sessionName)
+		if err != nil {
+			return nil, err
 		}
 
 		return Session(session), nil
 	})
 }
+
+// ParseSession returns the session of a JSON name (e.g., london, new-york).
+func ParseSession(name string) (*common.Session, error) {
+	// TODO: more dynamic
+	switch name {
+	case "london":
+		return common.LondonSession, nil
+	case "new-york":
+		return common.NYSession, nil
+	default:
+		return nil, fmt.Errorf("unknown session: %s", name)
+	}
+}
+
+// SessionName returns the JSON name of a session.
+func SessionName(session *common.Session) (string, error) {
+	switch session {
+	case common.LondonSession:
+		return "london", nil
+	case common.NYSession:
+		return "new-york", nil
+	default:
+		return "", fmt.Errorf("unknown session: %+v", session)
+	}
+}
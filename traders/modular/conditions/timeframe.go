@@ -0,0 +1,53 @@
+package conditions
+
+import (
+	"encoding/json"
+	"fmt"
+	"trading-bot/brokers"
+	"trading-bot/traders/modular/context"
+	"trading-bot/traders/modular/formatter"
+	"trading-bot/traders/modular/marshal"
+)
+
+// OnTimeframe evaluates the condition on the candles of a timeframe: its indicators, candles and history.
+// For example, OnTimeframe(H4, HistoryUsable()) waits for a full history of H4 candles.
+func OnTimeframe(timeframe brokers.Timeframe, condition Condition) Condition {
+	return newCondition(
+		func(ctx context.TraderContext) bool {
+			return condition.Execute(ctx.Timeframe(timeframe))
+		},
+		func() *formatter.FormatterNode {
+			return formatter.FormatWithChildren(fmt.Sprintf("On%s", timeframe), condition)
+		},
+		func() (string, any) {
+			return "onTimeframe", map[string]any{
+				"timeframe": timeframe.String(),
+				"condition": marshal.ToJSON(condition),
+			}
+		},
+	)
+}
+
+func init() {
+	jsonParsers.RegisterParser("onTimeframe", func(arg json.RawMessage) (Condition, error) {
+		var params struct {
+			Timeframe string          `json:"timeframe"`
+			Condition json.RawMessage `json:"condition"`
+		}
+		if err := json.Unmarshal(arg, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse OnTimeframe parameters: %w", err)
+		}
+
+		timeframe, err := brokers.ParseTimeframe(params.Timeframe)
+		if err != nil {
+			return nil, err
+		}
+
+		condition, err := FromJSON(params.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse condition: %w", err)
+		}
+
+		return OnTimeframe(timeframe, condition), nil
+	})
+}
@@ -3,6 +3,7 @@ package context
 import (
 	"time"
 	"trading-bot/brokers"
+	"trading-bot/traders/modular/snapshot"
 	"trading-bot/traders/tools"
 )
 
@@ -12,8 +13,15 @@ type TraderContext interface {
 	OpenPositions() []brokers.Position
 	IndicatorCache() IndicatorCache
 
+	// Recorder is not nil while the trader captures an entry snapshot.
+	Recorder() *snapshot.Recorder
+
 	Timestamp() time.Time
 	EntryPrice() float64
+
+	// Timeframe returns the context whose history and indicators are of the candles of a timeframe.
+	// The history of the trader is of one-minute candles.
+	Timeframe(timeframe brokers.Timeframe) TraderContext
 }
 
 type IndicatorCache interface {
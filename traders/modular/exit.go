@@ -0,0 +1,127 @@
+package modular
+
+import (
+	"fmt"
+	"time"
+	"trading-bot/brokers"
+	"trading-bot/common"
+	"trading-bot/traders/modular/conditions"
+	"trading-bot/traders/modular/formatter"
+)
+
+// exitRules close positions before their stop loss or take profit. Zero-valued rules are disabled.
+type exitRules struct {
+	closeLong      conditions.Condition
+	closeShort     conditions.Condition
+	maxHoldingTime time.Duration
+	weekendMargin  *time.Duration // Before the weekly close of the market
+	session        *common.Session
+	sessionMargin  time.Duration // Before the end of the session
+	reverse        bool
+}
+
+func (e *exitRules) empty() bool {
+	return e.closeLong == nil && e.closeShort == nil && e.maxHoldingTime == 0 &&
+		e.weekendMargin == nil && e.session == nil && !e.reverse
+}
+
+func (e *exitRules) Format() *formatter.FormatterNode {
+	var nodes []*formatter.FormatterNode
+
+	if e.closeLong != nil {
+		nodes = append(nodes, formatter.FormatWithChildren("CloseLong", e.closeLong))
+	}
+	if e.closeShort != nil {
+		nodes = append(nodes, formatter.FormatWithChildren("CloseShort", e.closeShort))
+	}
+	if e.maxHoldingTime != 0 {
+		nodes = append(nodes, formatter.Format(fmt.Sprintf("MaxHoldingTime: %s", e.maxHoldingTime)))
+	}
+	if e.weekendMargin != nil {
+		nodes = append(nodes, formatter.Format(fmt.Sprintf("CloseBeforeWeekend: %s", *e.weekendMargin)))
+	}
+	if e.session != nil {
+		nodes = append(nodes, formatter.Format(fmt.Sprintf("CloseAtSessionEnd: %s, %s", e.session, e.sessionMargin)))
+	}
+	if e.reverse {
+		nodes = append(nodes, formatter.Format("ReverseOnOppositeSignal"))
+	}
+
+	return formatter.Format("Exit", nodes...)
+}
+
+// closingWindow returns why the trader closes its positions and does not enter at this time, or an empty string.
+func (e *exitRules) closingWindow(now time.Time) string {
+	if e.weekendMargin != nil {
+		weekClose := common.WeekClose(now)
+		if !now.Before(weekClose.Add(-*e.weekendMargin)) && !now.After(weekClose) {
+			return "weekend"
+		}
+	}
+
+	if e.session != nil {
+		end := e.session.End(now)
+		if !now.Before(end.Add(-e.sessionMargin)) && !now.After(end) {
+			return fmt.Sprintf("end of session %s", e.session)
+		}
+	}
+
+	return ""
+}
+
+// exit closes the open positions whose exit rules are met, and returns false if the trader must not enter.
+func (t *trader) exit() bool {
+	now := t.Timestamp()
+	window := t.exitRules.closingWindow(now)
+
+	if len(t.openPositions) == 0 {
+		return window == ""
+	}
+
+	// Signals are evaluated once per direction, only with open positions: their histories are full
+	signals := make(map[brokers.PositionDirection]string, 2)
+	signal := func(direction brokers.PositionDirection) string {
+		if reason, ok := signals[direction]; ok {
+			return reason
+		}
+		signals[direction] = t.exitSignal(direction)
+		return signals[direction]
+	}
+
+	for pos := range t.openPositions {
+		reason := window
+		if reason == "" && t.exitRules.maxHoldingTime != 0 && now.Sub(pos.OpenTime()) >= t.exitRules.maxHoldingTime {
+			reason = "max holding time"
+		}
+		if reason == "" {
+			reason = signal(pos.Direction())
+		}
+		if reason == "" {
+			continue
+		}
+
+		if err := t.broker.ClosePosition(pos, reason); err != nil {
+			log.Error("Failed to close position: %v", err)
+			continue
+		}
+		delete(t.openPositions, pos)
+	}
+
+	return window == ""
+}
+
+// exitSignal returns why the positions of a direction are closed by a condition, or an empty string.
+func (t *trader) exitSignal(direction brokers.PositionDirection) string {
+	condition, opposite := t.exitRules.closeLong, t.shortTrigger
+	if direction == brokers.PositionDirectionShort {
+		condition, opposite = t.exitRules.closeShort, t.longTrigger
+	}
+
+	if condition != nil && condition.Execute(t) {
+		return condition.Format().Compact()
+	}
+	if t.exitRules.reverse && opposite != nil && opposite.Execute(t) {
+		return "opposite signal"
+	}
+	return ""
+}
@@ -33,6 +33,11 @@ func FormatWithChildren[T Formatter](value string, children ...T) *FormatterNode
 	return node
 }
 
+// Label returns the value of the node, without its children.
+func (n *FormatterNode) Label() string {
+	return n.value
+}
+
 func (n *FormatterNode) Compact() string {
 	if len(n.children) == 0 {
 		return n.value
@@ -57,9 +57,15 @@ func (i *indicator) Values(ctx context.TraderContext) []float64 {
 	c := ctx.IndicatorCache().(*cache)
 	key := i.format().Compact()
 
-	return c.access(key, func() []float64 {
+	values := c.access(key, func() []float64 {
 		return i.compute(ctx)
 	})
+
+	if recorder := ctx.Recorder(); recorder != nil {
+		recorder.RecordIndicator(i.format(), values)
+	}
+
+	return values
 }
 
 func (i *indicator) Format() *formatter.FormatterNode {
@@ -0,0 +1,63 @@
+package indicators
+
+import (
+	"encoding/json"
+	"fmt"
+	"trading-bot/brokers"
+	"trading-bot/traders/modular/context"
+	"trading-bot/traders/modular/formatter"
+	"trading-bot/traders/modular/marshal"
+	"trading-bot/traders/modular/snapshot"
+)
+
+// OnTimeframe computes the indicator on the candles of a timeframe, e.g., an EMA(200) of H4 candles.
+func OnTimeframe(timeframe brokers.Timeframe, indicator Indicator) Indicator {
+	return newIndicator(
+		func(ctx context.TraderContext) []float64 {
+			// Recorded once, with the timeframe
+			return indicator.Values(&unrecorded{ctx.Timeframe(timeframe)})
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Format(fmt.Sprintf("On%s", timeframe), indicator.Format())
+		},
+		func() (string, any) {
+			return "onTimeframe", map[string]any{
+				"timeframe": timeframe.String(),
+				"indicator": marshal.ToJSON(indicator),
+			}
+		},
+	)
+}
+
+// unrecorded is a context without recorder.
+type unrecorded struct {
+	context.TraderContext
+}
+
+func (u *unrecorded) Recorder() *snapshot.Recorder {
+	return nil
+}
+
+func init() {
+	jsonParsers.RegisterParser("onTimeframe", func(arg json.RawMessage) (Indicator, error) {
+		var params struct {
+			Timeframe string          `json:"timeframe"`
+			Indicator json.RawMessage `json:"indicator"`
+		}
+		if err := json.Unmarshal(arg, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse OnTimeframe parameters: %w", err)
+		}
+
+		timeframe, err := brokers.ParseTimeframe(params.Timeframe)
+		if err != nil {
+			return nil, err
+		}
+
+		indicator, err := FromJSON(params.Indicator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse indicator: %w", err)
+		}
+
+		return OnTimeframe(timeframe, indicator), nil
+	})
+}
@@ -3,19 +3,39 @@ package modular
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+	"trading-bot/brokers"
 	"trading-bot/traders/modular/conditions"
+	"trading-bot/traders/modular/management"
 	"trading-bot/traders/modular/marshal"
 	"trading-bot/traders/modular/ordercomputer"
 )
 
 type builderJSON struct {
-	HistorySize      int             `json:"historySize"`
-	Filter           json.RawMessage `json:"filter"`
-	LongTrigger      json.RawMessage `json:"longTrigger"`
-	ShortTrigger     json.RawMessage `json:"shortTrigger"`
-	StopLoss         json.RawMessage `json:"stopLoss"`
-	TakeProfit       json.RawMessage `json:"takeProfit"`
-	CapitalAllocator json.RawMessage `json:"capitalAllocator"`
+	HistorySize      int               `json:"historySize"`
+	TimeframeSizes   map[string]int    `json:"timeframeHistorySizes,omitempty"` // By timeframe (e.g., H4), omitted by default
+	Filter           json.RawMessage   `json:"filter"`
+	LongTrigger      json.RawMessage   `json:"longTrigger"`
+	ShortTrigger     json.RawMessage   `json:"shortTrigger"`
+	StopLoss         json.RawMessage   `json:"stopLoss"`
+	TakeProfit       json.RawMessage   `json:"takeProfit"`
+	StopManagement   []json.RawMessage `json:"stopManagement,omitempty"`
+	CapitalAllocator json.RawMessage   `json:"capitalAllocator"`
+	Exit             *exitJSON         `json:"exit,omitempty"` // Omitted without exit rules, keeping the JSON of older traders
+}
+
+type exitJSON struct {
+	CloseLong               json.RawMessage `json:"closeLong,omitempty"`
+	CloseShort              json.RawMessage `json:"closeShort,omitempty"`
+	MaxHoldingTime          string          `json:"maxHoldingTime,omitempty"`     // e.g., 4h
+	CloseBeforeWeekend      string          `json:"closeBeforeWeekend,omitempty"` // Margin, e.g., 30m, or 0s at the close
+	SessionEnd              *sessionEndJSON `json:"sessionEnd,omitempty"`
+	ReverseOnOppositeSignal bool            `json:"reverseOnOppositeSignal,omitempty"`
+}
+
+type sessionEndJSON struct {
+	Session string `json:"session"` // e.g., london
+	Margin  string `json:"margin"`  // e.g., 15m
 }
 
 func FromJSON(jsonData []byte) (Builder, error) {
@@ -29,6 +49,14 @@ func FromJSON(jsonData []byte) (Builder, error) {
 		historySize: bjson.HistorySize,
 	}
 
+	for name, size := range bjson.TimeframeSizes {
+		timeframe, err := brokers.ParseTimeframe(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse timeframe history size: %w", err)
+		}
+		res.SetTimeframeHistorySize(timeframe, size)
+	}
+
 	res.filter, err = conditions.FromJSON(bjson.Filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse filter condition: %w", err)
@@ -59,9 +87,99 @@ func FromJSON(jsonData []byte) (Builder, error) {
 		return nil, fmt.Errorf("failed to parse capital allocator order computer: %w", err)
 	}
 
+	for _, spec := range bjson.StopManagement {
+		rule, err := management.FromJSON(spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse stop management rule: %w", err)
+		}
+		res.stopManagement = append(res.stopManagement, rule)
+	}
+
+	if bjson.Exit != nil {
+		if res.exit, err = exitFromJSON(bjson.Exit); err != nil {
+			return nil, fmt.Errorf("failed to parse exit rules: %w", err)
+		}
+	}
+
 	return res, nil
 }
 
+func exitFromJSON(ejson *exitJSON) (exitRules, error) {
+	var exit exitRules
+	var err error
+
+	if ejson.CloseLong != nil {
+		if exit.closeLong, err = conditions.FromJSON(ejson.CloseLong); err != nil {
+			return exit, fmt.Errorf("failed to parse close long condition: %w", err)
+		}
+	}
+
+	if ejson.CloseShort != nil {
+		if exit.closeShort, err = conditions.FromJSON(ejson.CloseShort); err != nil {
+			return exit, fmt.Errorf("failed to parse close short condition: %w", err)
+		}
+	}
+
+	if ejson.MaxHoldingTime != "" {
+		if exit.maxHoldingTime, err = time.ParseDuration(ejson.MaxHoldingTime); err != nil {
+			return exit, fmt.Errorf("failed to parse max holding time: %w", err)
+		}
+	}
+
+	if ejson.CloseBeforeWeekend != "" {
+		margin, err := time.ParseDuration(ejson.CloseBeforeWeekend)
+		if err != nil {
+			return exit, fmt.Errorf("failed to parse weekend margin: %w", err)
+		}
+		exit.weekendMargin = &margin
+	}
+
+	if ejson.SessionEnd != nil {
+		if exit.session, err = conditions.ParseSession(ejson.SessionEnd.Session); err != nil {
+			return exit, err
+		}
+		if ejson.SessionEnd.Margin != "" {
+			if exit.sessionMargin, err = time.ParseDuration(ejson.SessionEnd.Margin); err != nil {
+				return exit, fmt.Errorf("failed to parse session end margin: %w", err)
+			}
+		}
+	}
+
+	exit.reverse = ejson.ReverseOnOppositeSignal
+
+	return exit, nil
+}
+
+func exitToJSON(exit *exitRules) *exitJSON {
+	if exit.empty() {
+		return nil
+	}
+
+	ejson := &exitJSON{ReverseOnOppositeSignal: exit.reverse}
+
+	if exit.closeLong != nil {
+		ejson.CloseLong = marshal.ToJSON(exit.closeLong)
+	}
+	if exit.closeShort != nil {
+		ejson.CloseShort = marshal.ToJSON(exit.closeShort)
+	}
+	if exit.maxHoldingTime != 0 {
+		ejson.MaxHoldingTime = exit.maxHoldingTime.String()
+	}
+	if exit.weekendMargin != nil {
+		ejson.CloseBeforeWeekend = exit.weekendMargin.String()
+	}
+	if exit.session != nil {
+		session, err := conditions.SessionName(exit.session)
+		if err != nil {
+			panic(err)
+		}
+		ejson.SessionEnd = &sessionEndJSON{Session: session, Margin: exit.sessionMargin.String()}
+	}
+
+	return ejson
+}
+
 func ToJSON(b Builder) string {
 	bu := b.(*builder)
 
@@ -73,6 +191,16 @@ func ToJSON(b Builder) string {
 		StopLoss:         marshal.ToJSON(bu.stopLoss),
 		TakeProfit:       marshal.ToJSON(bu.takeProfit),
 		CapitalAllocator: marshal.ToJSON(bu.capitalAllocator),
+		Exit:             exitToJSON(&bu.exit),
+	}
+	for timeframe, size := range bu.timeframeSizes {
+		if bjson.TimeframeSizes == nil {
+			bjson.TimeframeSizes = make(map[string]int)
+		}
+		bjson.TimeframeSizes[timeframe.String()] = size
+	}
+	for _, rule := range bu.stopManagement {
+		bjson.StopManagement = append(bjson.StopManagement, marshal.ToJSON(rule))
 	}
 
 	data, err := json.Marshal(bjson)
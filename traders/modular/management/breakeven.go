@@ -0,0 +1,92 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"trading-bot/brokers"
+	"trading-bot/traders/modular/context"
+	"trading-bot/traders/modular/formatter"
+)
+
+// BreakEven moves the stop loss to the open price once the profit reaches afterR multiples of the initial risk.
+func BreakEven(afterR float64) Rule {
+	return newRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			profit, ok := profitR(ctx, position)
+			if !ok || profit < afterR {
+				return 0, false
+			}
+			return position.OpenPrice(), true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Format("BreakEven", formatter.Format(fmt.Sprintf("AfterR: %.2f", afterR)))
+		},
+		func() (string, any) {
+			return "breakEven", map[string]any{
+				"afterR": afterR,
+			}
+		},
+	)
+}
+
+func init() {
+	jsonParsers.RegisterParser("breakEven", func(arg json.RawMessage) (Rule, error) {
+		var params struct {
+			AfterR float64 `json:"afterR"`
+		}
+
+		if err := json.Unmarshal(arg, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse BreakEven parameters: %w", err)
+		}
+		if params.AfterR <= 0 {
+			return nil, fmt.Errorf("break-even must be after a positive profit, got %.2fR", params.AfterR)
+		}
+
+		return BreakEven(params.AfterR), nil
+	})
+}
+
+// StepTrailing moves the stop loss by stepR multiples of the initial risk, each time the profit grows by as much:
+// with a step of 1R, the stop is at break-even after 1R of profit, at +1R after 2R, and so on.
+func StepTrailing(stepR float64) Rule {
+	return newRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			profit, ok := profitR(ctx, position)
+			if !ok {
+				return 0, false
+			}
+
+			steps := math.Floor(profit / stepR)
+			if steps < 1 {
+				return 0, false
+			}
+			return position.InitialStopLoss() + sign(position.Direction())*steps*stepR*risk(position), true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Format("StepTrailing", formatter.Format(fmt.Sprintf("StepR: %.2f", stepR)))
+		},
+		func() (string, any) {
+			return "stepTrailing", map[string]any{
+				"stepR": stepR,
+			}
+		},
+	)
+}
+
+func init() {
+	jsonParsers.RegisterParser("stepTrailing", func(arg json.RawMessage) (Rule, error) {
+		var params struct {
+			StepR float64 `json:"stepR"`
+		}
+
+		if err := json.Unmarshal(arg, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse StepTrailing parameters: %w", err)
+		}
+		if params.StepR <= 0 {
+			return nil, fmt.Errorf("trailing step must be positive, got %.2fR", params.StepR)
+		}
+
+		return StepTrailing(params.StepR), nil
+	})
+}
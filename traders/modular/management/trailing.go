@@ -0,0 +1,173 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"trading-bot/brokers"
+	"trading-bot/traders/modular/context"
+	"trading-bot/traders/modular/formatter"
+	"trading-bot/traders/modular/indicators"
+	"trading-bot/traders/modular/marshal"
+
+	"github.com/markcheno/go-talib"
+)
+
+// ATRTrailing trails the stop loss at a multiple of the ATR from the current price.
+func ATRTrailing(atr indicators.Indicator, multiplier float64) Rule {
+	return newRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			currAtr, ok := last(atr.Values(ctx))
+			if !ok {
+				return 0, false
+			}
+			return ctx.EntryPrice() - sign(position.Direction())*currAtr*multiplier, true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Format("ATRTrailing",
+				atr.Format(),
+				formatter.Format(fmt.Sprintf("Multiplier: %.4f", multiplier)),
+			)
+		},
+		func() (string, any) {
+			return "atrTrailing", map[string]any{
+				"atr":        marshal.ToJSON(atr),
+				"multiplier": multiplier,
+			}
+		},
+	)
+}
+
+func init() {
+	jsonParsers.RegisterParser("atrTrailing", func(arg json.RawMessage) (Rule, error) {
+		var params struct {
+			ATR        json.RawMessage `json:"atr"`
+			Multiplier float64         `json:"multiplier"`
+		}
+
+		if err := json.Unmarshal(arg, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse ATRTrailing parameters: %w", err)
+		}
+		if params.Multiplier <= 0 {
+			return nil, fmt.Errorf("ATR trailing multiplier must be positive, got %.4f", params.Multiplier)
+		}
+
+		atr, err := indicators.FromJSON(params.ATR)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ATR indicator: %w", err)
+		}
+
+		return ATRTrailing(atr, params.Multiplier), nil
+	})
+}
+
+// Chandelier trails the stop loss at a multiple of the ATR from the highest high (long) or the lowest low (short)
+// of the last candles.
+func Chandelier(period int, atr indicators.Indicator, multiplier float64) Rule {
+	r := newRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			history := ctx.HistoricalData()
+			if history.Len() < period {
+				return 0, false
+			}
+
+			currAtr, ok := last(atr.Values(ctx))
+			if !ok {
+				return 0, false
+			}
+
+			if position.Direction() == brokers.PositionDirectionLong {
+				return history.GetHighest(period) - currAtr*multiplier, true
+			}
+			return history.GetLowest(period) + currAtr*multiplier, true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Format("Chandelier",
+				formatter.Format(fmt.Sprintf("Period: %d", period)),
+				atr.Format(),
+				formatter.Format(fmt.Sprintf("Multiplier: %.4f", multiplier)),
+			)
+		},
+		func() (string, any) {
+			return "chandelier", map[string]any{
+				"period":     period,
+				"atr":        marshal.ToJSON(atr),
+				"multiplier": multiplier,
+			}
+		},
+	)
+	r.historySize = period
+	return r
+}
+
+func init() {
+	jsonParsers.RegisterParser("chandelier", func(arg json.RawMessage) (Rule, error) {
+		var params struct {
+			Period     int             `json:"period"`
+			ATR        json.RawMessage `json:"atr"`
+			Multiplier float64         `json:"multiplier"`
+		}
+
+		if err := json.Unmarshal(arg, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse Chandelier parameters: %w", err)
+		}
+		if params.Period <= 0 {
+			return nil, fmt.Errorf("chandelier period must be positive, got %d", params.Period)
+		}
+		if params.Multiplier <= 0 {
+			return nil, fmt.Errorf("chandelier multiplier must be positive, got %.4f", params.Multiplier)
+		}
+
+		atr, err := indicators.FromJSON(params.ATR)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ATR indicator: %w", err)
+		}
+
+		return Chandelier(params.Period, atr, params.Multiplier), nil
+	})
+}
+
+// ParabolicSAR trails the stop loss at the parabolic SAR: its acceleration factor grows by acceleration
+// with each new extreme of the trend, up to maximum.
+// The SAR follows the trend of the candles, not the position: it does not move the stop while on the wrong side of the price.
+func ParabolicSAR(acceleration, maximum float64) Rule {
+	return newRule(
+		func(ctx context.TraderContext, position brokers.Position) (float64, bool) {
+			history := ctx.HistoricalData()
+			sar, ok := last(talib.Sar(history.GetHighPrices().All(), history.GetLowPrices().All(), acceleration, maximum))
+			if !ok || (sar-ctx.EntryPrice())*sign(position.Direction()) >= 0 {
+				return 0, false
+			}
+			return sar, true
+		},
+		func() *formatter.FormatterNode {
+			return formatter.Format("ParabolicSAR",
+				formatter.Format(fmt.Sprintf("Acceleration: %.4f", acceleration)),
+				formatter.Format(fmt.Sprintf("Maximum: %.4f", maximum)),
+			)
+		},
+		func() (string, any) {
+			return "parabolicSAR", map[string]any{
+				"acceleration": acceleration,
+				"maximum":      maximum,
+			}
+		},
+	)
+}
+
+func init() {
+	jsonParsers.RegisterParser("parabolicSAR", func(arg json.RawMessage) (Rule, error) {
+		var params struct {
+			Acceleration float64 `json:"acceleration"`
+			Maximum      float64 `json:"maximum"`
+		}
+
+		if err := json.Unmarshal(arg, &params); err != nil {
+			return nil, fmt.Errorf("failed to parse ParabolicSAR parameters: %w", err)
+		}
+		if params.Acceleration <= 0 || params.Maximum < params.Acceleration {
+			return nil, fmt.Errorf("invalid parabolic SAR acceleration %.4f and maximum %.4f", params.Acceleration, params.Maximum)
+		}
+
+		return ParabolicSAR(params.Acceleration, params.Maximum), nil
+	})
+}
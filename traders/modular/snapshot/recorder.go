@@ -0,0 +1,103 @@
+package snapshot
+
+import (
+	"trading-bot/brokers"
+	"trading-bot/traders/modular/formatter"
+)
+
+// Recorder captures the evaluation of a condition tree: each condition's result and each indicator's current value.
+type Recorder struct {
+	root  *brokers.OrderReason
+	stack []*frame
+}
+
+type frame struct {
+	node          *brokers.OrderReason
+	format        *formatter.FormatterNode
+	hasConditions bool
+	indicators    map[string]struct{}
+}
+
+// NewRecorder creates a recorder whose captured nodes are attached under a root node with the given label.
+func NewRecorder(label string) *Recorder {
+	root := &brokers.OrderReason{Label: label}
+
+	return &Recorder{
+		root:  root,
+		stack: []*frame{{node: root, hasConditions: true, indicators: make(map[string]struct{})}},
+	}
+}
+
+// Root returns the captured tree.
+func (r *Recorder) Root() *brokers.OrderReason {
+	return r.root
+}
+
+// Section opens a labelled node under which the next conditions are recorded, and returns a function to close it.
+func (r *Recorder) Section(label string) func() {
+	r.push(&brokers.OrderReason{Label: label}, nil)
+
+	return func() {
+		r.pop()
+	}
+}
+
+// BeginCondition starts recording a condition.
+func (r *Recorder) BeginCondition(format *formatter.FormatterNode) {
+	r.current().hasConditions = true
+	r.push(&brokers.OrderReason{}, format)
+}
+
+// EndCondition ends recording the current condition with its result.
+func (r *Recorder) EndCondition(result bool) {
+	f := r.current()
+	f.node.Result = &result
+
+	// Composite conditions (And, Or, ...) show their children, leaves show their parameters
+	if f.hasConditions {
+		f.node.Label = f.format.Label()
+	} else {
+		f.node.Label = f.format.Compact()
+	}
+
+	r.pop()
+}
+
+// RecordIndicator records the current value of an indicator, once per condition.
+func (r *Recorder) RecordIndicator(format *formatter.FormatterNode, values []float64) {
+	if len(values) == 0 {
+		return
+	}
+
+	f := r.current()
+	label := format.Compact()
+	if _, exists := f.indicators[label]; exists {
+		return
+	}
+	f.indicators[label] = struct{}{}
+
+	value := values[len(values)-1]
+	f.node.Children = append(f.node.Children, &brokers.OrderReason{
+		Label: label,
+		Value: &value,
+	})
+}
+
+func (r *Recorder) current() *frame {
+	return r.stack[len(r.stack)-1]
+}
+
+func (r *Recorder) push(node *brokers.OrderReason, format *formatter.FormatterNode) {
+	parent := r.current()
+	parent.node.Children = append(parent.node.Children, node)
+
+	r.stack = append(r.stack, &frame{
+		node:       node,
+		format:     format,
+		indicators: make(map[string]struct{}),
+	})
+}
+
+func (r *Recorder) pop() {
+	r.stack = r.stack[:len(r.stack)-1]
+}
@@ -0,0 +1,78 @@
+package modular
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+	"trading-bot/brokers"
+)
+
+// HistoryDuration returns the duration of market data that fills the histories of the trader, the longest of its timeframes.
+// The trader does not trade before the histories of its higher timeframes are full.
+func HistoryDuration(b Builder) (time.Duration, error) {
+	bu, err := getBuilder(b)
+	if err != nil {
+		return 0, err
+	}
+
+	timeframes, err := readTimeframes(bu)
+	if err != nil {
+		return 0, err
+	}
+
+	duration := time.Duration(bu.historySize) * time.Minute
+	for _, timeframe := range timeframes {
+		duration = max(duration, time.Duration(bu.timeframeHistorySize(timeframe))*time.Duration(timeframe))
+	}
+	return duration, nil
+}
+
+// readTimeframes returns the timeframes read by the components of the trader, besides one minute.
+// Every component is serializable, so they are the timeframes of the onTimeframe objects of its JSON.
+func readTimeframes(b *builder) ([]brokers.Timeframe, error) {
+	var root any
+	if err := json.Unmarshal([]byte(ToJSON(b)), &root); err != nil {
+		return nil, fmt.Errorf("failed to read timeframes: %w", err)
+	}
+
+	var timeframes []brokers.Timeframe
+
+	var walk func(node any) error
+	walk = func(node any) error {
+		switch node := node.(type) {
+		case []any:
+			for _, child := range node {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+
+		case map[string]any:
+			if params, ok := node["onTimeframe"].(map[string]any); ok && len(node) == 1 {
+				name, _ := params["timeframe"].(string)
+				timeframe, err := brokers.ParseTimeframe(name)
+				if err != nil {
+					return err
+				}
+				if timeframe != brokers.Timeframe1Minute && !slices.Contains(timeframes, timeframe) {
+					timeframes = append(timeframes, timeframe)
+				}
+			}
+
+			for _, child := range node {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+
+	slices.Sort(timeframes)
+	return timeframes, nil
+}
@@ -10,7 +10,9 @@ import (
 	"trading-bot/traders/modular/conditions"
 	"trading-bot/traders/modular/context"
 	"trading-bot/traders/modular/indicators"
+	"trading-bot/traders/modular/management"
 	"trading-bot/traders/modular/ordercomputer"
+	"trading-bot/traders/modular/snapshot"
 	"trading-bot/traders/tools"
 )
 
@@ -25,6 +27,14 @@ func Setup(broker brokers.Broker, builder Builder) error {
 
 	log.Debug("%s", builder.Format().Detailed())
 
+	// Higher timeframes are called first by the broker, so that the trader ticks with their closed candles
+	for timeframe, c := range trader.timeframes {
+		broker.RegisterMarketDataCallback(timeframe, func(candle brokers.Candle) {
+			c.history.AddCandle(candle)
+			c.indicatorCache.Tick()
+		})
+	}
+
 	broker.RegisterMarketDataCallback(brokers.Timeframe1Minute, func(candle brokers.Candle) {
 		trader.tick(candle)
 	})
@@ -42,7 +52,28 @@ type trader struct {
 	shortTrigger     conditions.Condition
 	stopLoss         ordercomputer.OrderComputer
 	takeProfit       ordercomputer.OrderComputer
+	stopManagement   []management.Rule
 	capitalAllocator ordercomputer.OrderComputer
+	exitRules        exitRules
+	recorder         *snapshot.Recorder
+
+	// Contexts of the timeframes read by the strategy, besides one minute
+	timeframes map[brokers.Timeframe]*timeframeContext
+}
+
+// timeframeContext is the context of the candles of a timeframe: its history and indicators.
+type timeframeContext struct {
+	*trader
+	history        *tools.History
+	indicatorCache context.IndicatorCache
+}
+
+func (c *timeframeContext) HistoricalData() *tools.History {
+	return c.history
+}
+
+func (c *timeframeContext) IndicatorCache() context.IndicatorCache {
+	return c.indicatorCache
 }
 
 func newTrader(broker brokers.Broker, builder Builder) (*trader, error) {
@@ -70,7 +101,19 @@ func newTrader(broker brokers.Broker, builder Builder) (*trader, error) {
 		return nil, fmt.Errorf("capital allocator must be set")
 	}
 
-	return &trader{
+	for _, rule := range b.stopManagement {
+		if rule.HistorySize() > b.historySize {
+			return nil, fmt.Errorf("stop management %s reads %d candles, more than the history size %d",
+				rule.Format().Compact(), rule.HistorySize(), b.historySize)
+		}
+	}
+
+	timeframes, err := readTimeframes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &trader{
 		broker:           broker,
 		history:          tools.NewHistory(b.historySize),
 		openPositions:    make(map[brokers.Position]struct{}),
@@ -80,8 +123,30 @@ func newTrader(broker brokers.Broker, builder Builder) (*trader, error) {
 		shortTrigger:     b.shortTrigger,
 		stopLoss:         b.stopLoss,
 		takeProfit:       b.takeProfit,
+		stopManagement:   b.stopManagement,
 		capitalAllocator: b.capitalAllocator,
-	}, nil
+		exitRules:        b.exit,
+		timeframes:       make(map[brokers.Timeframe]*timeframeContext, len(timeframes)),
+	}
+
+	for timeframe, size := range b.timeframeSizes {
+		if !slices.Contains(timeframes, timeframe) {
+			return nil, fmt.Errorf("history size of timeframe %s, which is not read by the strategy", timeframe)
+		}
+		if size <= 0 {
+			return nil, fmt.Errorf("history size of timeframe %s must be greater than 0", timeframe)
+		}
+	}
+
+	for _, timeframe := range timeframes {
+		t.timeframes[timeframe] = &timeframeContext{
+			trader:         t,
+			history:        tools.NewHistory(b.timeframeHistorySize(timeframe)),
+			indicatorCache: indicators.NewCache(),
+		}
+	}
+
+	return t, nil
 }
 
 // workaround builder naming conflict
@@ -104,6 +169,23 @@ func (t *trader) tick(candle brokers.Candle) {
 
 	t.indicatorCache.Tick()
 
+	if candle.WarmUp {
+		return
+	}
+
+	// Histories of higher timeframes take longer to fill, their indicators need full histories (see HistoryDuration)
+	for _, c := range t.timeframes {
+		if !c.history.IsFull() {
+			return
+		}
+	}
+
+	t.manageStops()
+
+	if !t.exit() {
+		return
+	}
+
 	if !t.filter.Execute(t) {
 		return
 	}
@@ -148,7 +230,13 @@ func (t *trader) takePosition(direction brokers.PositionDirection) {
 		return
 	}
 
-	// TODO: reason
+	switch direction {
+	case brokers.PositionDirectionLong:
+		order.Reason = t.longTrigger.Format().Compact()
+	case brokers.PositionDirectionShort:
+		order.Reason = t.shortTrigger.Format().Compact()
+	}
+	order.ReasonDetails = t.snapshot(direction)
 
 	pos, err := t.broker.PlaceOrder(order)
 	if err != nil {
@@ -159,6 +247,32 @@ func (t *trader) takePosition(direction brokers.PositionDirection) {
 	t.openPositions[pos] = struct{}{}
 }
 
+// snapshot evaluates again the filter and triggers while recording each condition's result and indicator's value.
+// Indicators are cached for the current candle, so this does not recompute them.
+func (t *trader) snapshot(direction brokers.PositionDirection) *brokers.OrderReason {
+	t.recorder = snapshot.NewRecorder(fmt.Sprintf("Entry (%s)", direction.String()))
+	defer func() {
+		t.recorder = nil
+	}()
+
+	sections := []struct {
+		label     string
+		condition conditions.Condition
+	}{
+		{"Filter", t.filter},
+		{"LongTrigger", t.longTrigger},
+		{"ShortTrigger", t.shortTrigger},
+	}
+
+	for _, section := range sections {
+		end := t.recorder.Section(section.label)
+		section.condition.Execute(t)
+		end()
+	}
+
+	return t.recorder.Root()
+}
+
 var _ context.TraderContext = (*trader)(nil)
 
 func (t *trader) Broker() brokers.Broker {
@@ -176,6 +290,10 @@ func (t *trader) IndicatorCache() context.IndicatorCache {
 	return t.indicatorCache
 }
 
+func (t *trader) Recorder() *snapshot.Recorder {
+	return t.recorder
+}
+
 func (t *trader) Timestamp() time.Time {
 	return t.broker.GetCurrentTime()
 }
@@ -183,3 +301,16 @@ func (t *trader) Timestamp() time.Time {
 func (t *trader) EntryPrice() float64 {
 	return t.history.GetPrice()
 }
+
+func (t *trader) Timeframe(timeframe brokers.Timeframe) context.TraderContext {
+	if timeframe == brokers.Timeframe1Minute {
+		return t
+	}
+
+	c, ok := t.timeframes[timeframe]
+	if !ok {
+		// Timeframes are collected from the strategy
+		panic(fmt.Sprintf("timeframe %s is not read by the strategy", timeframe))
+	}
+	return c
+}
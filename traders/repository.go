@@ -3,12 +3,16 @@ package traders
 import (
 	"trading-bot/brokers"
 	"trading-bot/traders/basic"
+	"trading-bot/traders/benchmark"
 	"trading-bot/traders/expression"
 	"trading-bot/traders/gpt"
 	"trading-bot/traders/modular"
 )
 
 type GptConfig = gpt.Config
+type RandomEntryConfig = benchmark.RandomEntryConfig
+type OrderTemplate = benchmark.OrderTemplate
+type BuyAndHoldConfig = benchmark.BuyAndHoldConfig
 
 func SetupBasicTrader(broker brokers.Broker) {
 	basic.Setup(broker)
@@ -25,3 +29,11 @@ func SetupModularTrader(broker brokers.Broker, builder modular.Builder) error {
 func SetupExpressionTrader(broker brokers.Broker, config *expression.Configuration) error {
 	return expression.Setup(broker, config)
 }
+
+func SetupBuyAndHoldTrader(broker brokers.Broker, config *BuyAndHoldConfig) {
+	benchmark.SetupBuyAndHold(broker, config)
+}
+
+func SetupRandomEntryTrader(broker brokers.Broker, config *RandomEntryConfig) {
+	benchmark.SetupRandomEntry(broker, config)
+}
@@ -0,0 +1,112 @@
+package traders
+
+import (
+	"fmt"
+	"time"
+	"trading-bot/brokers"
+	"trading-bot/traders/expression"
+	"trading-bot/traders/modular"
+)
+
+type StrategyKind string
+
+const (
+	StrategyKindModular    StrategyKind = "modular"
+	StrategyKindExpression StrategyKind = "expression"
+)
+
+// Strategy is a trader configuration that can be set up on a broker and identified in a stable way,
+// so that its backtest results can be cached and queried.
+type Strategy interface {
+	Kind() StrategyKind
+
+	// Identity is a stable serialized description of the strategy.
+	// Two strategies with the same identity must trade the same way.
+	Identity() string
+
+	// Description is a short human-readable description, for logs.
+	Description() string
+
+	// Setup registers the trader on the broker.
+	Setup(broker brokers.Broker) error
+
+	// HistoryDuration is the duration of market data that fills the histories of the trader.
+	HistoryDuration() (time.Duration, error)
+}
+
+type modularStrategy struct {
+	builder modular.Builder
+}
+
+func NewModularStrategy(builder modular.Builder) Strategy {
+	return &modularStrategy{builder}
+}
+
+func (s *modularStrategy) Kind() StrategyKind {
+	return StrategyKindModular
+}
+
+func (s *modularStrategy) Identity() string {
+	return modular.ToJSON(s.builder)
+}
+
+func (s *modularStrategy) Description() string {
+	return s.builder.Format().Compact()
+}
+
+func (s *modularStrategy) Setup(broker brokers.Broker) error {
+	return SetupModularTrader(broker, s.builder)
+}
+
+func (s *modularStrategy) HistoryDuration() (time.Duration, error) {
+	return modular.HistoryDuration(s.builder)
+}
+
+type expressionStrategy struct {
+	config *expression.Configuration
+}
+
+func NewExpressionStrategy(config *expression.Configuration) Strategy {
+	return &expressionStrategy{config}
+}
+
+func (s *expressionStrategy) Kind() StrategyKind {
+	return StrategyKindExpression
+}
+
+// Identity implements Strategy.
+// Expression configurations are not serializable, but their formatted form lists every parameter.
+func (s *expressionStrategy) Identity() string {
+	return s.config.Format().Compact()
+}
+
+func (s *expressionStrategy) Description() string {
+	return s.config.Format().Compact()
+}
+
+func (s *expressionStrategy) Setup(broker brokers.Broker) error {
+	return SetupExpressionTrader(broker, s.config)
+}
+
+func (s *expressionStrategy) HistoryDuration() (time.Duration, error) {
+	return s.config.HistoryDuration(), nil
+}
+
+// StrategyFromIdentity rebuilds a strategy from its kind and identity.
+// Only modular strategies can be rebuilt, as expression configurations are not serializable.
+func StrategyFromIdentity(kind StrategyKind, identity string) (Strategy, error) {
+	switch kind {
+	case StrategyKindModular:
+		builder, err := modular.FromJSON([]byte(identity))
+		if err != nil {
+			return nil, err
+		}
+		return NewModularStrategy(builder), nil
+
+	case StrategyKindExpression:
+		return nil, fmt.Errorf("expression strategies cannot be rebuilt from their identity")
+
+	default:
+		return nil, fmt.Errorf("unknown strategy kind: %s", kind)
+	}
+}
@@ -30,6 +30,16 @@ func (h *History) IsUsable() bool {
 	return true
 }
 
+// Len returns the number of candles of the history.
+func (h *History) Len() int {
+	return len(h.candles)
+}
+
+// IsFull returns true if the history has its maximum number of candles, usable or not.
+func (h *History) IsFull() bool {
+	return len(h.candles) >= h.maxSize
+}
+
 func (h *History) AddCandle(candle brokers.Candle) {
 	if len(h.candles) >= h.maxSize {
 		h.candles = h.candles[1:] // Remove the oldest candle
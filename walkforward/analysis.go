@@ -0,0 +1,290 @@
+package walkforward
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"trading-bot/common"
+	"trading-bot/gridsearch"
+	"trading-bot/runner"
+	"trading-bot/traders"
+)
+
+var log = common.NewLogger("walkforward")
+
+type Config struct {
+	Instrument string
+	Windows    []*Window
+	Objective  runner.Objective // Chooses the best combination of each in-sample period
+	Continuous bool             // Run each period at once, instead of month by month
+	Settings   *runner.Settings
+}
+
+// StrategyBuilder returns the strategy of a combination. It is called for each submission, as strategies may hold state.
+type StrategyBuilder func(combo gridsearch.Combo) (traders.Strategy, error)
+
+type WindowResult struct {
+	*Window
+	Best        gridsearch.Combo      // nil if no combination has results over the whole in-sample period
+	InSample    *runner.StrategyStats // Of the best combination
+	OutOfSample *runner.StrategyStats // nil if the best combination has no results over the whole out-of-sample period
+	Efficiency  float64               // Out-of-sample over in-sample mean monthly PnL, NaN if undefined
+}
+
+// EquityPoint is a month of the stitched out-of-sample equity.
+type EquityPoint struct {
+	Month  string
+	PnL    float64
+	Equity float64 // At the end of the month
+}
+
+// ParameterStability describes how the best value of a parameter changes between windows.
+type ParameterStability struct {
+	Parameter string
+	Mode      interface{} // Most chosen value
+	ModeShare float64     // Percentage of the windows choosing the mode
+	Distinct  int         // Number of chosen values
+	Changes   int         // Number of windows choosing another value than the previous window
+}
+
+type Report struct {
+	Windows    []*WindowResult
+	Equity     []*EquityPoint // Months of the out-of-sample periods, with the best combination of their window
+	Efficiency float64        // Mean out-of-sample over mean in-sample monthly PnL, NaN if undefined
+	Stability  []*ParameterStability
+}
+
+// Run optimises each in-sample period over the combinations, and trades the best one on the following out-of-sample period.
+// All in-sample runs are submitted at once, then all out-of-sample runs, so that they run in parallel.
+func Run(ctx context.Context, r *runner.Runner, config *Config, combos []gridsearch.Combo, strategy StrategyBuilder) (*Report, error) {
+	if len(combos) == 0 {
+		return nil, fmt.Errorf("no combination to optimise")
+	}
+
+	// In-sample optimisation
+	batch := r.NewBatch()
+	for _, window := range config.Windows {
+		for _, combo := range combos {
+			s, err := strategy(combo)
+			if err != nil {
+				return nil, err
+			}
+			if err := submit(batch, config, s, window.InSampleFrom, window.InSampleTo); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := batch.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]*WindowResult, len(config.Windows))
+	for i, window := range config.Windows {
+		result, err := bestInSample(r, config, window, combos, strategy)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+
+	// Out-of-sample trading
+	batch = r.NewBatch()
+	for _, result := range results {
+		if result.Best == nil {
+			log.Warning("No combination has results over %s, skipping window", result.InSampleRange())
+			continue
+		}
+		s, err := strategy(result.Best)
+		if err != nil {
+			return nil, err
+		}
+		if err := submit(batch, config, s, result.OutOfSampleFrom, result.OutOfSampleTo); err != nil {
+			return nil, err
+		}
+	}
+	if err := batch.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	report := &Report{Windows: results}
+	equity := config.Settings.Broker.InitialCapital
+
+	var inSamplePnL, outOfSamplePnL float64
+	var inSampleWindows, outOfSampleMonths int
+
+	for _, result := range results {
+		if result.Best == nil {
+			continue
+		}
+
+		s, err := strategy(result.Best)
+		if err != nil {
+			return nil, err
+		}
+		months := common.Months(result.OutOfSampleFrom, result.OutOfSampleTo)
+
+		result.OutOfSample, err = stats(r, config, s, result.OutOfSampleFrom, result.OutOfSampleTo)
+		if err != nil {
+			return nil, err
+		}
+		if result.OutOfSample == nil {
+			log.Warning("Best combination of %s has no results over %s", result.InSampleRange(), result.OutOfSampleRange())
+			result.Efficiency = math.NaN()
+			continue
+		}
+
+		result.Efficiency = efficiency(result.OutOfSample.MeanMonthlyPnL, result.InSample.MeanMonthlyPnL)
+
+		inSamplePnL += result.InSample.MeanMonthlyPnL
+		inSampleWindows++
+
+		for _, month := range months {
+			monthStats, err := r.StrategyStats(s, filter(config, result.OutOfSampleFrom, result.OutOfSampleTo, month, month))
+			if err != nil {
+				return nil, err
+			}
+			if monthStats == nil {
+				return nil, fmt.Errorf("no results of %s in %s", month, result.OutOfSampleRange())
+			}
+
+			equity += monthStats.NetPnL
+			outOfSamplePnL += monthStats.NetPnL
+			outOfSampleMonths++
+
+			report.Equity = append(report.Equity, &EquityPoint{Month: month.String(), PnL: monthStats.NetPnL, Equity: equity})
+		}
+	}
+
+	report.Efficiency = math.NaN()
+	if inSampleWindows > 0 && outOfSampleMonths > 0 {
+		report.Efficiency = efficiency(outOfSamplePnL/float64(outOfSampleMonths), inSamplePnL/float64(inSampleWindows))
+	}
+
+	report.Stability = stability(results)
+
+	return report, nil
+}
+
+// bestInSample returns the window with the combination of the best objective over the in-sample period.
+func bestInSample(r *runner.Runner, config *Config, window *Window, combos []gridsearch.Combo, strategy StrategyBuilder) (*WindowResult, error) {
+	result := &WindowResult{Window: window, Efficiency: math.NaN()}
+	best := math.Inf(-1)
+
+	for _, combo := range combos {
+		s, err := strategy(combo)
+		if err != nil {
+			return nil, err
+		}
+
+		inSample, err := stats(r, config, s, window.InSampleFrom, window.InSampleTo)
+		if err != nil {
+			return nil, err
+		}
+		if inSample == nil {
+			continue
+		}
+
+		if value := config.Objective.Value(inSample); result.Best == nil || value > best {
+			result.Best, result.InSample, best = combo, inSample, value
+		}
+	}
+
+	return result, nil
+}
+
+func submit(batch *runner.Batch, config *Config, strategy traders.Strategy, from, to common.Month) error {
+	if config.Continuous {
+		return batch.SubmitRange(config.Instrument, from, to, strategy, config.Settings)
+	}
+
+	for _, month := range common.Months(from, to) {
+		if err := batch.SubmitRun(config.Instrument, month, strategy, config.Settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stats returns the stats of a strategy over a period, nil if some months have no results.
+func stats(r *runner.Runner, config *Config, strategy traders.Strategy, from, to common.Month) (*runner.StrategyStats, error) {
+	periodStats, err := r.StrategyStats(strategy, filter(config, from, to, from, to))
+	if err != nil {
+		return nil, err
+	}
+
+	if periodStats == nil || periodStats.Months != len(common.Months(from, to)) {
+		return nil, nil
+	}
+	return periodStats, nil
+}
+
+// filter selects the months from first to last, of the runs submitted for the period from from to to.
+func filter(config *Config, from, to, first, last common.Month) runner.RunFilter {
+	return runner.RunFilter{
+		Instrument: config.Instrument,
+		From:       first.String(),
+		To:         last.String(),
+		DataSource: string(config.Settings.DataSource),
+		Settings:   config.Settings.Identity(),
+		RunRanges:  runner.RunRanges(from, to, config.Continuous),
+	}
+}
+
+// efficiency returns the ratio of out-of-sample to in-sample performance, NaN if the in-sample performance is not positive.
+func efficiency(outOfSample, inSample float64) float64 {
+	if inSample <= 0 {
+		return math.NaN()
+	}
+	return outOfSample / inSample
+}
+
+func stability(results []*WindowResult) []*ParameterStability {
+	var chosen []gridsearch.Combo
+	for _, result := range results {
+		if result.Best != nil {
+			chosen = append(chosen, result.Best)
+		}
+	}
+	if len(chosen) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(chosen[0]))
+	for key := range chosen[0] {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	stabilities := make([]*ParameterStability, len(keys))
+	for i, key := range keys {
+		counts := make(map[string]int)
+		values := make(map[string]interface{})
+		s := &ParameterStability{Parameter: key}
+
+		for j, combo := range chosen {
+			value := fmt.Sprintf("%v", combo[key])
+			counts[value]++
+			values[value] = combo[key]
+
+			if j > 0 && value != fmt.Sprintf("%v", chosen[j-1][key]) {
+				s.Changes++
+			}
+		}
+
+		// Ties are broken by the first chosen value
+		best := 0
+		for _, combo := range chosen {
+			value := fmt.Sprintf("%v", combo[key])
+			if counts[value] > best {
+				s.Mode, best = values[value], counts[value]
+			}
+		}
+
+		s.Distinct = len(counts)
+		s.ModeShare = float64(best) / float64(len(chosen)) * 100
+		stabilities[i] = s
+	}
+
+	return stabilities
+}
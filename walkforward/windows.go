@@ -0,0 +1,73 @@
+package walkforward
+
+import (
+	"fmt"
+	"trading-bot/common"
+)
+
+// Mode is how the in-sample window moves forward.
+type Mode string
+
+const (
+	ModeRolling  Mode = "rolling"  // The in-sample window keeps its length, and moves with the out-of-sample window
+	ModeAnchored Mode = "anchored" // The in-sample window always starts at the first month, and grows
+)
+
+func ParseMode(value string) (Mode, error) {
+	switch Mode(value) {
+	case ModeRolling, ModeAnchored:
+		return Mode(value), nil
+	default:
+		return "", fmt.Errorf("unknown walk-forward mode: %s", value)
+	}
+}
+
+// Window is an in-sample period, optimised, followed by an out-of-sample period, traded with the best combination.
+type Window struct {
+	InSampleFrom, InSampleTo       common.Month
+	OutOfSampleFrom, OutOfSampleTo common.Month
+}
+
+func (w *Window) InSampleRange() string {
+	return common.FormatMonthRange(w.InSampleFrom, w.InSampleTo)
+}
+
+func (w *Window) OutOfSampleRange() string {
+	return common.FormatMonthRange(w.OutOfSampleFrom, w.OutOfSampleTo)
+}
+
+// Windows splits the months from from to to in windows of inSample months followed by outOfSample months.
+// Out-of-sample periods follow each other, so that they cover the months after the first in-sample period once.
+func Windows(from, to common.Month, inSample, outOfSample int, mode Mode) ([]*Window, error) {
+	if inSample < 1 || outOfSample < 1 {
+		return nil, fmt.Errorf("in-sample and out-of-sample periods must be 1 month at least")
+	}
+
+	var windows []*Window
+
+	for start := from; ; start = start.AddMonths(outOfSample) {
+		window := &Window{
+			InSampleFrom:    start,
+			InSampleTo:      start.AddMonths(inSample - 1),
+			OutOfSampleFrom: start.AddMonths(inSample),
+			OutOfSampleTo:   start.AddMonths(inSample + outOfSample - 1),
+		}
+
+		if to.Before(window.OutOfSampleTo) {
+			break
+		}
+
+		if mode == ModeAnchored {
+			window.InSampleFrom = from
+		}
+
+		windows = append(windows, window)
+	}
+
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("%s is too short for %d in-sample and %d out-of-sample months",
+			common.FormatMonthRange(from, to), inSample, outOfSample)
+	}
+
+	return windows, nil
+}